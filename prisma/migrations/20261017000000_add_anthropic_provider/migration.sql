@@ -0,0 +1,2 @@
+-- AlterEnum
+ALTER TYPE "ProviderUsed" ADD VALUE 'anthropic';
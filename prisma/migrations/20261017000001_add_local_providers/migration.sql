@@ -0,0 +1,3 @@
+-- AlterEnum
+ALTER TYPE "ProviderUsed" ADD VALUE 'ollama';
+ALTER TYPE "ProviderUsed" ADD VALUE 'mock';
@@ -0,0 +1,58 @@
+// Package cache stores generation results so identical prompts are not sent
+// to an LLM twice within the cache window (BR-007, BR-008).
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+)
+
+// DefaultTTL is how long a generation result stays cached (BR-007).
+const DefaultTTL = time.Hour
+
+// keyPrefix matches the gen_cache:{hash} layout documented in the FSD.
+const keyPrefix = "gen_cache:"
+
+// Cache is a store of generation results keyed by Key.
+type Cache interface {
+	// Get returns the cached result for key. The boolean is false on a miss.
+	Get(ctx context.Context, key string) (models.GenerationResult, bool, error)
+	// Set stores result under key for ttl.
+	Set(ctx context.Context, key string, result models.GenerationResult, ttl time.Duration) error
+}
+
+// NormalizePrompt lowercases a prompt and collapses all runs of whitespace so
+// trivially different spellings of the same request share a cache entry.
+func NormalizePrompt(prompt string) string {
+	return strings.Join(strings.Fields(strings.ToLower(prompt)), " ")
+}
+
+// Key returns the cache key for a generation request. The output format is part
+// of the key so html_css and tailwind results are never served for one another
+// (BR-008). Style hints, allowed origins and a pinned provider/model change the
+// output too, so they are hashed alongside the normalized prompt.
+func Key(req models.GenerationRequest) string {
+	format := req.Preferences.OutputFormat
+	if format == "" {
+		format = "html_css"
+	}
+
+	h := sha256.New()
+	h.Write([]byte(NormalizePrompt(req.Prompt)))
+	h.Write([]byte{0})
+	h.Write([]byte(NormalizePrompt(req.Preferences.StyleHints)))
+	h.Write([]byte{0})
+	h.Write([]byte(req.PreferredProvider + "/" + req.PreferredModel))
+	h.Write([]byte{0})
+	origins := append([]string(nil), req.Preferences.AllowedOrigins...)
+	sort.Strings(origins)
+	h.Write([]byte(strings.ToLower(strings.Join(origins, ","))))
+
+	return keyPrefix + format + ":" + hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,58 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/cache"
+	"github.com/zest-app/ai-service/models"
+)
+
+func req(prompt, format string) models.GenerationRequest {
+	return models.GenerationRequest{
+		Prompt:      prompt,
+		Preferences: models.GenPrefs{OutputFormat: format},
+	}
+}
+
+func TestKey_NormalizesPrompt(t *testing.T) {
+	a := cache.Key(req("A landing page  for a\tcoffee shop", "html_css"))
+	b := cache.Key(req("  a landing page for a coffee SHOP ", "html_css"))
+	if a != b {
+		t.Errorf("expected equal keys for normalized prompts, got %q and %q", a, b)
+	}
+}
+
+func TestKey_DefaultFormatIsHTMLCSS(t *testing.T) {
+	if cache.Key(req("a landing page", "")) != cache.Key(req("a landing page", "html_css")) {
+		t.Error("expected empty output format to key as html_css")
+	}
+}
+
+func TestKey_SeparatesFormats(t *testing.T) {
+	// BR-008: tailwind results must never be served for html_css requests
+	if cache.Key(req("a landing page", "html_css")) == cache.Key(req("a landing page", "tailwind")) {
+		t.Error("expected different keys for different output formats")
+	}
+}
+
+func TestMemoryCache_ExpiresAfterTTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := cache.NewMemoryCache().WithClock(func() time.Time { return now })
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "k", models.GenerationResult{HTML: "<p>hi</p>"}, time.Hour); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	got, ok, err := c.Get(ctx, "k")
+	if err != nil || !ok || got.HTML != "<p>hi</p>" {
+		t.Fatalf("expected hit, got ok=%v err=%v result=%+v", ok, err, got)
+	}
+
+	now = now.Add(time.Hour)
+	if _, ok, _ := c.Get(ctx, "k"); ok {
+		t.Error("expected entry to expire after TTL")
+	}
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/ttlmap"
+)
+
+// DefaultMaxEntries bounds a MemoryCache; past it, the results closest to
+// expiry are evicted first.
+const DefaultMaxEntries = 1000
+
+// MemoryCache is an in-process Cache used in tests and when REDIS_URL is not
+// configured. Expired entries are swept periodically, and at most
+// DefaultMaxEntries results are kept.
+type MemoryCache struct {
+	entries *ttlmap.Map[models.GenerationResult]
+}
+
+// NewMemoryCache creates an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: ttlmap.New[models.GenerationResult]().WithMaxEntries(DefaultMaxEntries),
+	}
+}
+
+// WithClock replaces the time source, allowing tests to expire entries
+// without sleeping.
+func (c *MemoryCache) WithClock(now func() time.Time) *MemoryCache {
+	c.entries.WithClock(now)
+	return c
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (models.GenerationResult, bool, error) {
+	result, ok := c.entries.Get(key)
+	return result, ok, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, result models.GenerationResult, ttl time.Duration) error {
+	c.entries.Set(key, result, ttl)
+	return nil
+}
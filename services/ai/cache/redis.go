@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zest-app/ai-service/models"
+)
+
+// RedisCache stores generation results as JSON strings in Redis, relying on
+// key expiry for the TTL.
+type RedisCache struct {
+	client *redis.Client
+}
+
+// NewRedisCache creates a RedisCache from a redis:// URL (e.g. REDIS_URL).
+func NewRedisCache(url string) (*RedisCache, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("cache: parse redis url: %w", err)
+	}
+	return &RedisCache{client: redis.NewClient(opts)}, nil
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (models.GenerationResult, bool, error) {
+	var result models.GenerationResult
+
+	b, err := c.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return result, false, nil
+	}
+	if err != nil {
+		return result, false, fmt.Errorf("cache: redis get: %w", err)
+	}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return result, false, fmt.Errorf("cache: decode entry: %w", err)
+	}
+	return result, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, result models.GenerationResult, ttl time.Duration) error {
+	b, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("cache: encode entry: %w", err)
+	}
+	if err := c.client.Set(ctx, key, b, ttl).Err(); err != nil {
+		return fmt.Errorf("cache: redis set: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package coalesce deduplicates concurrent identical work: callers asking for
+// the same key while a call is in flight wait for its result instead of
+// starting their own.
+package coalesce
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs at most one call per key at a time. The zero value is ready to
+// use.
+//
+// Unlike a plain singleflight, a call is not tied to the caller that started
+// it: it runs on a context detached from every caller and is canceled only
+// once all of its waiters have given up, so the first client disconnecting
+// does not fail everyone else.
+type Group[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[T]
+}
+
+type call[T any] struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	val     T
+	err     error
+}
+
+// Do returns the result of fn for key, starting fn unless a call for key is
+// already in flight. shared reports whether the result came from a call
+// another caller started. If ctx ends first Do returns ctx.Err(); the call
+// keeps running for the remaining waiters.
+//
+// fn receives a context that carries ctx's values but not its deadline or
+// cancellation; it should apply its own timeout.
+func (g *Group[T]) Do(ctx context.Context, key string, fn func(context.Context) (T, error)) (v T, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call[T])
+	}
+	c, shared := g.calls[key]
+	if !shared {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &call[T]{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+		go g.run(callCtx, key, c, fn)
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, shared, c.err
+	case <-ctx.Done():
+		g.leave(key, c)
+		return v, shared, ctx.Err()
+	}
+}
+
+// run executes fn and publishes its result.
+func (g *Group[T]) run(ctx context.Context, key string, c *call[T], fn func(context.Context) (T, error)) {
+	c.val, c.err = fn(ctx)
+	g.mu.Lock()
+	g.forget(key, c)
+	g.mu.Unlock()
+	c.cancel()
+	close(c.done)
+}
+
+// leave drops a waiter, canceling the call when nobody is left to receive it.
+func (g *Group[T]) leave(key string, c *call[T]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c.waiters--
+	if c.waiters == 0 {
+		// Later callers must start afresh rather than join a canceled call.
+		g.forget(key, c)
+		c.cancel()
+	}
+}
+
+// forget removes c from the in-flight set if it is still registered for key.
+func (g *Group[T]) forget(key string, c *call[T]) {
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
+
+// InFlight returns the number of calls currently running.
+func (g *Group[T]) InFlight() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.calls)
+}
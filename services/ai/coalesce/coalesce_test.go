@@ -0,0 +1,127 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForWaiters blocks until n callers are waiting on key.
+func waitForWaiters(t *testing.T, g *Group[string], key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		c := g.calls[key]
+		got := 0
+		if c != nil {
+			got = c.waiters
+		}
+		g.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d waiters on %q", n, key)
+}
+
+func TestDo_FollowersShareOneCall(t *testing.T) {
+	var g Group[string]
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func(context.Context) (string, error) {
+		calls.Add(1)
+		<-release
+		return "page", nil
+	}
+
+	var wg sync.WaitGroup
+	var sharedCount atomic.Int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, shared, err := g.Do(context.Background(), "k", fn)
+			if err != nil || v != "page" {
+				t.Errorf("Do = %q, %v", v, err)
+			}
+			if shared {
+				sharedCount.Add(1)
+			}
+		}()
+	}
+	waitForWaiters(t, &g, "k", 5)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 || sharedCount.Load() != 4 {
+		t.Errorf("calls = %d, shared = %d; want 1 call and 4 followers", calls.Load(), sharedCount.Load())
+	}
+	if g.InFlight() != 0 {
+		t.Errorf("InFlight() = %d after completion", g.InFlight())
+	}
+}
+
+func TestDo_LeaderDisconnectDoesNotCancelFollowers(t *testing.T) {
+	var g Group[string]
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		select {
+		case <-release:
+			return "page", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	leaderCtx, leaderGone := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, _, err := g.Do(leaderCtx, "k", fn)
+		leaderErr <- err
+	}()
+	waitForWaiters(t, &g, "k", 1)
+
+	follower := make(chan string, 1)
+	go func() {
+		v, _, _ := g.Do(context.Background(), "k", fn)
+		follower <- v
+	}()
+	waitForWaiters(t, &g, "k", 2)
+
+	leaderGone()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("leader err = %v, want canceled", err)
+	}
+	close(release)
+	if v := <-follower; v != "page" {
+		t.Errorf("follower got %q, want the shared result", v)
+	}
+}
+
+func TestDo_LastWaiterLeavingCancelsCall(t *testing.T) {
+	var g Group[string]
+	canceled := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go g.Do(ctx, "k", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		close(canceled)
+		return "", ctx.Err()
+	})
+	waitForWaiters(t, &g, "k", 1)
+	cancel()
+
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("call was not canceled after its only waiter left")
+	}
+	v, shared, err := g.Do(context.Background(), "k", func(context.Context) (string, error) { return "fresh", nil })
+	if v != "fresh" || shared || err != nil {
+		t.Errorf("Do after cancel = %q, shared=%v, %v; want a fresh call", v, shared, err)
+	}
+}
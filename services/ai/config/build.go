@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/zest-app/ai-service/providers"
+)
+
+// factory builds a provider of one type from its configuration and resolved
+// settings. check, if set, validates type-specific fields.
+type factory struct {
+	build func(ProviderConfig, providers.Settings) (providers.Provider, error)
+	check func(ProviderConfig) error
+}
+
+// builtin adapts a constructor that needs nothing beyond Settings.
+func builtin[P providers.Provider](newProvider func(providers.Settings) P) factory {
+	return factory{build: func(_ ProviderConfig, s providers.Settings) (providers.Provider, error) {
+		return newProvider(s), nil
+	}}
+}
+
+// factories maps a ProviderConfig type to its implementation.
+var factories = map[string]factory{
+	"glm":       builtin(providers.NewGLMProvider),
+	"gemini":    builtin(providers.NewGeminiProvider),
+	"copilot":   builtin(providers.NewCopilotProvider),
+	"anthropic": builtin(providers.NewAnthropicProvider),
+	"ollama":    builtin(providers.NewOllamaProvider),
+	"openai":    {build: buildOpenAI, check: checkOpenAI},
+	"mock":      {build: buildMock},
+}
+
+// buildMock creates a mock provider, loading canned responses from
+// FixturesDir when set.
+func buildMock(pc ProviderConfig, s providers.Settings) (providers.Provider, error) {
+	var responses map[string]string
+	var fallback string
+	if pc.FixturesDir != "" {
+		var err error
+		if responses, fallback, err = providers.LoadMockResponses(pc.FixturesDir); err != nil {
+			return nil, fmt.Errorf("config: provider %q: %w", pc.Name, err)
+		}
+	}
+	return providers.NewMockProvider(pc.Name, s, responses, fallback), nil
+}
+
+func buildOpenAI(pc ProviderConfig, s providers.Settings) (providers.Provider, error) {
+	return providers.NewOpenAICompatibleProvider(providers.OpenAICompatibleConfig{
+		Name:       pc.Name,
+		Settings:   s,
+		AuthScheme: pc.AuthScheme,
+		AuthHeader: pc.AuthHeader,
+		Headers:    pc.Headers,
+	}), nil
+}
+
+func checkOpenAI(pc ProviderConfig) error {
+	if pc.BaseURL == "" {
+		return errors.New("base_url is required")
+	}
+	if pc.DefaultModel == "" && len(pc.Models) == 0 {
+		return errors.New("default_model or models is required")
+	}
+	switch pc.AuthScheme {
+	case "", providers.AuthBearer, providers.AuthNone:
+	case providers.AuthHeader:
+		if pc.AuthHeader == "" {
+			return errors.New("auth_header is required with auth_scheme header")
+		}
+	default:
+		return fmt.Errorf("unknown auth_scheme %q", pc.AuthScheme)
+	}
+	return nil
+}
+
+// Build constructs the enabled providers in configuration order, ready to be
+// passed to providers.NewRouter or Router.Reload.
+func (c Config) Build() ([]providers.Provider, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	var out []providers.Provider
+	for _, pc := range c.Providers {
+		if !pc.enabled() {
+			continue
+		}
+		s, err := pc.settings()
+		if err != nil {
+			return nil, err
+		}
+		if cc := c.Cassettes; cc != nil {
+			s = withCassette(s, cc, pc.Name)
+		}
+		p, err := factories[pc.kind()].build(pc, s)
+		if err != nil {
+			return nil, err
+		}
+		if p.Name() != pc.Name {
+			return nil, fmt.Errorf("config: provider %q: type %q is always named %q", pc.Name, pc.kind(), p.Name())
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// withCassette routes a provider's HTTP traffic through a cassette stored in
+// its own subdirectory. When replaying, a placeholder key enables providers
+// whose real credentials are absent, as they are in CI.
+func withCassette(s providers.Settings, cc *CassetteConfig, name string) providers.Settings {
+	c, _ := providers.NewCassette(cc.Mode, filepath.Join(cc.Dir, name)) // mode checked by Validate
+	s.Transport = c
+	if cc.Mode == providers.CassetteReplay && len(s.APIKeys) == 0 {
+		s.APIKey = "replay"
+	}
+	return s
+}
@@ -0,0 +1,281 @@
+// Package config loads the provider configuration file: which providers the
+// router uses and in what order (BR-005), their models, generation parameters,
+// timeouts and where their credentials come from.
+//
+// The file is YAML; since YAML is a superset of JSON, a JSON file works too.
+//
+//	providers:
+//	  - name: glm
+//	    api_key_env: GLM_API_KEY
+//	    default_model: glm-4.5-air
+//	    timeout: 45s
+//	    models:
+//	      - id: glm-4.5-air
+//	        display_name: GLM-4.5 Air
+//	        max_tokens: 4096
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zest-app/ai-service/providers"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the root of the configuration file.
+type Config struct {
+	// Providers are tried in the listed order (BR-005).
+	Providers []ProviderConfig `yaml:"providers"`
+	// Routing selects how the router orders providers.
+	Routing RoutingConfig `yaml:"routing"`
+	// Cassettes, when set, records every provider's HTTP exchanges or
+	// replays them offline.
+	Cassettes *CassetteConfig `yaml:"cassettes"`
+}
+
+// RoutingConfig configures provider selection.
+type RoutingConfig struct {
+	// Strategy is "static" (default: the listed order) or "adaptive"
+	// (ordered by observed success rate and latency, listed order breaking
+	// ties).
+	Strategy string `yaml:"strategy"`
+	// HedgeDelay, when set, starts the next provider concurrently if the
+	// current one has not answered in this long; the first valid result
+	// wins. Hedges count against BR-006. Streaming requests never hedge.
+	HedgeDelay time.Duration `yaml:"hedge_delay"`
+}
+
+// CassetteConfig enables record/replay of provider HTTP traffic. Exchanges
+// are stored under Dir/<provider name>/.
+type CassetteConfig struct {
+	// Mode is "record" or "replay".
+	Mode string `yaml:"mode"`
+	Dir  string `yaml:"dir"`
+}
+
+// ProviderConfig describes one provider instance.
+type ProviderConfig struct {
+	// Name identifies the provider in routing, /models and PreferredProvider.
+	Name string `yaml:"name"`
+	// Type selects the implementation; it defaults to Name. Use "openai" for
+	// any OpenAI-compatible endpoint (OpenAI, Together, Groq, vLLM, LM Studio).
+	Type string `yaml:"type"`
+	// Enabled defaults to true. A disabled provider is left out of the router.
+	Enabled *bool `yaml:"enabled"`
+
+	// APIKeyEnv names the environment variable holding the API key.
+	// APIKeyFile, when set, takes precedence and is read on every (re)load.
+	// Either may hold several keys separated by commas or newlines; requests
+	// then rotate through them per KeySelection ("round_robin", the default,
+	// or "least_used"), and keys the vendor rejects or rate-limits are set
+	// aside for a while.
+	APIKeyEnv    string `yaml:"api_key_env"`
+	APIKeyFile   string `yaml:"api_key_file"`
+	KeySelection string `yaml:"key_selection"`
+
+	BaseURL string `yaml:"base_url"`
+	// BaseURLEnv names an environment variable that overrides BaseURL when
+	// set, e.g. OLLAMA_HOST.
+	BaseURLEnv   string        `yaml:"base_url_env"`
+	DefaultModel string        `yaml:"default_model"`
+	Timeout      time.Duration `yaml:"timeout"`
+	Temperature  *float64      `yaml:"temperature"`
+	MaxTokens    int           `yaml:"max_tokens"`
+	Models       []ModelConfig `yaml:"models"`
+	// ModelDiscoveryTTL, when set, lists models from the provider's API and
+	// caches them this long; Models then supplies display names and
+	// capabilities, and is the fallback when listing fails.
+	ModelDiscoveryTTL time.Duration `yaml:"model_discovery_ttl"`
+
+	// AuthScheme is "bearer" (default), "header" or "none"; AuthHeader names
+	// the header for "header". Headers are sent with every request. These
+	// apply to the "openai" type only.
+	AuthScheme string            `yaml:"auth_scheme"`
+	AuthHeader string            `yaml:"auth_header"`
+	Headers    map[string]string `yaml:"headers"`
+
+	// FixturesDir holds canned responses for the "mock" type; see
+	// providers.LoadMockResponses.
+	FixturesDir string `yaml:"fixtures_dir"`
+}
+
+// ModelConfig describes a model offered by a provider. Temperature and
+// MaxTokens override the provider-level values for this model; the remaining
+// fields describe its capabilities in GET /models.
+type ModelConfig struct {
+	ID          string   `yaml:"id"`
+	DisplayName string   `yaml:"display_name"`
+	Temperature *float64 `yaml:"temperature"`
+	MaxTokens   int      `yaml:"max_tokens"`
+
+	ContextWindow   int  `yaml:"context_window"`
+	MaxOutputTokens int  `yaml:"max_output_tokens"`
+	Vision          bool `yaml:"vision"`
+}
+
+// Default returns the configuration used when no file is given: the built-in
+// providers in BR-005 order with their usual environment variables, then
+// Anthropic and a local Ollama server for offline development.
+func Default() Config {
+	return Config{Providers: []ProviderConfig{
+		{Name: "glm", APIKeyEnv: "GLM_API_KEY"},
+		{Name: "gemini", APIKeyEnv: "GEMINI_API_KEY"},
+		{Name: "copilot", APIKeyEnv: "GITHUB_COPILOT_TOKEN"},
+		{Name: "anthropic", APIKeyEnv: "ANTHROPIC_API_KEY"},
+		{Name: "ollama", BaseURLEnv: "OLLAMA_HOST"},
+	}}
+}
+
+// Load reads and validates the configuration file at path.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("config: read: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate checks for missing or duplicate names and unknown provider types.
+func (c Config) Validate() error {
+	if len(c.Providers) == 0 {
+		return errors.New("config: no providers configured")
+	}
+	switch c.Routing.Strategy {
+	case "", providers.StrategyStatic, providers.StrategyAdaptive:
+	default:
+		return fmt.Errorf("config: routing: unknown strategy %q", c.Routing.Strategy)
+	}
+	if c.Routing.HedgeDelay < 0 {
+		return errors.New("config: routing: hedge_delay must not be negative")
+	}
+	if cc := c.Cassettes; cc != nil {
+		if _, err := providers.NewCassette(cc.Mode, cc.Dir); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		if cc.Dir == "" {
+			return errors.New("config: cassettes: dir is required")
+		}
+	}
+	seen := make(map[string]bool, len(c.Providers))
+	for i, p := range c.Providers {
+		if p.Name == "" {
+			return fmt.Errorf("config: providers[%d]: name is required", i)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("config: provider %q listed twice", p.Name)
+		}
+		seen[p.Name] = true
+		switch p.KeySelection {
+		case "", providers.KeyRoundRobin, providers.KeyLeastUsed:
+		default:
+			return fmt.Errorf("config: provider %q: unknown key_selection %q", p.Name, p.KeySelection)
+		}
+		f, ok := factories[p.kind()]
+		if !ok {
+			return fmt.Errorf("config: provider %q: unknown type %q", p.Name, p.kind())
+		}
+		if f.check != nil {
+			if err := f.check(p); err != nil {
+				return fmt.Errorf("config: provider %q: %w", p.Name, err)
+			}
+		}
+		for j, m := range p.Models {
+			if m.ID == "" {
+				return fmt.Errorf("config: provider %q: models[%d]: id is required", p.Name, j)
+			}
+		}
+	}
+	return nil
+}
+
+// kind returns the implementation type of p.
+func (p ProviderConfig) kind() string {
+	if p.Type != "" {
+		return p.Type
+	}
+	return p.Name
+}
+
+// enabled reports whether p should be built.
+func (p ProviderConfig) enabled() bool {
+	return p.Enabled == nil || *p.Enabled
+}
+
+// apiKeys resolves the provider's credentials. A missing environment variable
+// is not an error: the provider is built but reports itself disabled.
+func (p ProviderConfig) apiKeys() ([]string, error) {
+	var raw string
+	switch {
+	case p.APIKeyFile != "":
+		data, err := os.ReadFile(p.APIKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("config: provider %q: api key: %w", p.Name, err)
+		}
+		raw = string(data)
+	case p.APIKeyEnv != "":
+		raw = os.Getenv(p.APIKeyEnv)
+	}
+	var keys []string
+	for _, k := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+// settings converts p into provider Settings.
+func (p ProviderConfig) settings() (providers.Settings, error) {
+	keys, err := p.apiKeys()
+	if err != nil {
+		return providers.Settings{}, err
+	}
+	baseURL := p.BaseURL
+	if v := os.Getenv(p.BaseURLEnv); p.BaseURLEnv != "" && v != "" {
+		baseURL = v
+	}
+	s := providers.Settings{
+		APIKeys:      keys,
+		KeySelection: p.KeySelection,
+		BaseURL:      strings.TrimRight(baseURL, "/"),
+		DefaultModel: p.DefaultModel,
+		Timeout:      p.Timeout,
+		ModelsTTL:    p.ModelDiscoveryTTL,
+		Params:       providers.GenParams{Temperature: p.Temperature, MaxTokens: p.MaxTokens},
+	}
+	for _, m := range p.Models {
+		name := m.DisplayName
+		if name == "" {
+			name = m.ID
+		}
+		s.Models = append(s.Models, providers.ModelInfo{
+			ID:              m.ID,
+			DisplayName:     name,
+			Provider:        p.Name,
+			ContextWindow:   m.ContextWindow,
+			MaxOutputTokens: m.MaxOutputTokens,
+			Vision:          m.Vision,
+		})
+		if m.Temperature != nil || m.MaxTokens > 0 {
+			if s.ModelParams == nil {
+				s.ModelParams = make(map[string]providers.GenParams)
+			}
+			s.ModelParams[m.ID] = providers.GenParams{Temperature: m.Temperature, MaxTokens: m.MaxTokens}
+		}
+	}
+	if s.DefaultModel == "" && len(s.Models) > 0 {
+		s.DefaultModel = s.Models[0].ID
+	}
+	return s, nil
+}
@@ -0,0 +1,201 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zest-app/ai-service/config"
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/providers"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "providers.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_BuildsProvidersInOrder(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "gemini.key")
+	os.WriteFile(keyFile, []byte("file-key\n"), 0o600)
+	t.Setenv("TEST_GLM_KEY", "env-key")
+
+	path := writeConfig(t, `
+providers:
+  - name: copilot
+    enabled: false
+  - name: gemini
+    api_key_file: `+keyFile+`
+    models:
+      - id: gemini-2.5-pro
+        max_tokens: 2048
+  - name: glm
+    api_key_env: TEST_GLM_KEY
+    timeout: 45s
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	ps, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(ps) != 2 || ps[0].Name() != "gemini" || ps[1].Name() != "glm" {
+		t.Fatalf("unexpected providers: %v", ps)
+	}
+	for _, p := range ps {
+		if !p.Enabled() {
+			t.Errorf("%s: expected API key to be resolved", p.Name())
+		}
+	}
+	if m := ps[0].Models(); len(m) != 1 || m[0].ID != "gemini-2.5-pro" || m[0].DisplayName != "gemini-2.5-pro" {
+		t.Errorf("gemini models = %+v, want only the configured one", m)
+	}
+	if len(ps[1].Models()) == 0 {
+		t.Error("glm should keep its built-in models when none are configured")
+	}
+}
+
+func TestLoad_AcceptsJSON(t *testing.T) {
+	path := writeConfig(t, `{"providers": [{"name": "glm"}, {"name": "gemini"}]}`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Providers) != 2 {
+		t.Errorf("got %d providers, want 2", len(cfg.Providers))
+	}
+}
+
+func TestLoad_RejectsInvalidConfig(t *testing.T) {
+	cases := map[string]string{
+		"empty":        `providers: []`,
+		"unknown type": "providers:\n  - name: foo\n",
+		"duplicate":    "providers:\n  - name: glm\n  - name: glm\n",
+		"renamed":      "providers:\n  - name: fast\n    type: glm\n",
+		"key strategy": "providers:\n  - name: glm\n    key_selection: random\n",
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := config.Load(writeConfig(t, body))
+			if err == nil {
+				_, err = cfg.Build()
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), "config:") {
+				t.Errorf("expected config error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoad_PoolsSeveralKeys(t *testing.T) {
+	t.Setenv("TEST_GEMINI_KEYS", "key-aaaa1111, key-bbbb2222,,")
+	cfg, err := config.Load(writeConfig(t, `
+providers:
+  - name: gemini
+    api_key_env: TEST_GEMINI_KEYS
+    key_selection: least_used
+`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	ps, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	keys := ps[0].(providers.KeyedProvider).KeyStatus()
+	if len(keys) != 2 || keys[0].ID != "…1111" || keys[1].ID != "…2222" {
+		t.Errorf("key pool = %+v, want both keys", keys)
+	}
+}
+
+func TestDefault_FollowsBR005Order(t *testing.T) {
+	ps, err := config.Default().Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	var names []string
+	for _, p := range ps {
+		names = append(names, p.Name())
+	}
+	if got := strings.Join(names, ","); got != "glm,gemini,copilot,anthropic,ollama" {
+		t.Errorf("default order = %s, want glm,gemini,copilot,anthropic,ollama", got)
+	}
+}
+
+func TestLoad_OpenAICompatible(t *testing.T) {
+	path := writeConfig(t, `
+providers:
+  - name: lmstudio
+    type: openai
+    base_url: http://localhost:1234/v1/
+    auth_scheme: none
+    models:
+      - id: qwen2.5-coder
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	ps, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(ps) != 1 || ps[0].Name() != "lmstudio" || !ps[0].Enabled() {
+		t.Errorf("unexpected providers: %+v", ps)
+	}
+
+	for _, body := range []string{
+		"providers:\n  - name: x\n    type: openai\n    default_model: m\n",
+		"providers:\n  - name: x\n    type: openai\n    base_url: http://h\n",
+		"providers:\n  - name: x\n    type: openai\n    base_url: http://h\n    default_model: m\n    auth_scheme: header\n",
+	} {
+		if _, err := config.Load(writeConfig(t, body)); err == nil {
+			t.Errorf("expected error for %q", body)
+		}
+	}
+}
+
+func TestLoad_MockAndCassettes(t *testing.T) {
+	fixtures := t.TempDir()
+	os.WriteFile(filepath.Join(fixtures, "default.html"), []byte("<html>demo</html>"), 0o600)
+	t.Setenv("GLM_API_KEY", "")
+
+	path := writeConfig(t, `
+cassettes:
+  mode: replay
+  dir: `+t.TempDir()+`
+providers:
+  - name: glm
+    api_key_env: GLM_API_KEY
+  - name: demo
+    type: mock
+    fixtures_dir: `+fixtures+`
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	ps, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if !ps[0].Enabled() {
+		t.Error("expected replay mode to enable a provider without credentials")
+	}
+	resp, err := ps[1].Generate(context.Background(), models.GenerationRequest{Prompt: "anything"})
+	if err != nil || resp.Text != "<html>demo</html>" {
+		t.Errorf("mock fallback = %q, %v", resp.Text, err)
+	}
+
+	if _, err := config.Load(writeConfig(t, "cassettes:\n  mode: rewind\n  dir: x\nproviders:\n  - name: glm\n")); err == nil {
+		t.Error("expected an error for an unknown cassette mode")
+	}
+}
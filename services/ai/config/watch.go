@@ -0,0 +1,68 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultPollInterval is how often Watch checks the file for changes.
+const DefaultPollInterval = 5 * time.Second
+
+// Watch calls apply with the freshly loaded configuration whenever the file at
+// path changes or the process receives SIGHUP, until ctx is done. A file that
+// fails to load is logged and ignored so a bad edit never takes the service
+// down; the previous configuration stays in effect.
+func Watch(ctx context.Context, path string, interval time.Duration, apply func(Config) error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := fileVersion(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Printf("[ai-service] SIGHUP: reloading %s", path)
+		case <-ticker.C:
+			v := fileVersion(path)
+			if v == last {
+				continue
+			}
+			log.Printf("[ai-service] %s changed: reloading", path)
+		}
+		last = fileVersion(path)
+
+		cfg, err := Load(path)
+		if err == nil {
+			err = apply(cfg)
+		}
+		if err != nil {
+			log.Printf("[ai-service] config reload failed, keeping previous configuration: %v", err)
+		}
+	}
+}
+
+// version identifies a revision of a file well enough to notice edits.
+type version struct {
+	mod  time.Time
+	size int64
+}
+
+func fileVersion(path string) version {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return version{}
+	}
+	return version{mod: fi.ModTime(), size: fi.Size()}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/zest-app/ai-service/models"
+)
+
+// Batch limits. Items beyond the concurrency cap wait for a free slot; each
+// item has its own BR-003 timeout starting when it begins generating.
+const (
+	maxBatchItems           = 500
+	defaultBatchConcurrency = 4
+	maxBatchConcurrency     = 16
+)
+
+// BatchHandler handles POST /generate/batch, used to seed the template
+// gallery. The body is
+//
+//	{"requests": [GenerationRequest, ...], "concurrency": 4}
+//
+// and the response is NDJSON: one line per item in completion order, then a
+// summary line.
+//
+//	{"index":3,"request_id":"...","status_code":200,"result":GenerationResult}
+//	{"index":0,"request_id":"...","status_code":422,"error":{"error":"..."}}
+//	{"summary":{"total":2,"succeeded":1,"failed":1}}
+//
+// Each item is validated, moderated (BR-004), cached and coalesced exactly as
+// by POST /generate; a failed item does not affect the others. Only a
+// malformed batch is rejected as a whole, with a 400 before streaming starts.
+type BatchHandler struct {
+	gen *GenerateHandler
+}
+
+// NewBatchHandler creates a BatchHandler generating through gen, so batch
+// items share its cache and in-flight requests.
+func NewBatchHandler(gen *GenerateHandler) *BatchHandler {
+	return &BatchHandler{gen: gen}
+}
+
+type batchRequest struct {
+	Requests    []models.GenerationRequest `json:"requests"`
+	Concurrency int                        `json:"concurrency"`
+}
+
+// batchItem is one NDJSON line of the response. Result is set for items that
+// reached a provider, including failed generations; Error for items rejected
+// before that.
+type batchItem struct {
+	Index      int                      `json:"index"`
+	RequestID  string                   `json:"request_id"`
+	StatusCode int                      `json:"status_code"`
+	Result     *models.GenerationResult `json:"result,omitempty"`
+	Error      *models.ErrorResponse    `json:"error,omitempty"`
+}
+
+type batchSummary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+func (h *BatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var batch batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if len(batch.Requests) == 0 {
+		writeError(w, http.StatusBadRequest, "requests is required")
+		return
+	}
+	if len(batch.Requests) > maxBatchItems {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d requests per batch", maxBatchItems))
+		return
+	}
+	concurrency := batch.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	concurrency = min(concurrency, maxBatchConcurrency, len(batch.Requests))
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	indexes := make(chan int)
+	items := make(chan batchItem)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				items <- h.run(r.Context(), idx, batch.Requests[idx])
+			}
+		}()
+	}
+	go func() {
+		defer close(indexes)
+		for idx := range batch.Requests {
+			select {
+			case indexes <- idx:
+			case <-r.Context().Done():
+				return // client gone; stop starting new items
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(items)
+	}()
+
+	enc := json.NewEncoder(w)
+	summary := batchSummary{Total: len(batch.Requests)}
+	for item := range items {
+		if item.StatusCode == http.StatusOK {
+			summary.Succeeded++
+		}
+		enc.Encode(item)
+		flusher.Flush()
+	}
+	// Items never started because the client went away count as failed.
+	summary.Failed = summary.Total - summary.Succeeded
+	enc.Encode(map[string]batchSummary{"summary": summary})
+	flusher.Flush()
+}
+
+// run validates, moderates and generates one batch item.
+func (h *BatchHandler) run(ctx context.Context, idx int, req models.GenerationRequest) batchItem {
+	if req.RequestID == "" {
+		req.RequestID = uuid.New().String()
+	}
+	if req.UserID == "" {
+		req.UserID = "anonymous"
+	}
+	item := batchItem{Index: idx, RequestID: req.RequestID}
+	reject := func(status int, e models.ErrorResponse) batchItem {
+		item.StatusCode, item.Error = status, &e
+		return item
+	}
+
+	if req.Prompt == "" {
+		return reject(http.StatusBadRequest, models.ErrorResponse{Error: "prompt is required"})
+	}
+	if e := preferenceError(h.gen.router, req); e != nil {
+		return reject(http.StatusBadRequest, *e)
+	}
+	// BR-004: Moderation MUST run before LLM call
+	if decision := h.gen.mod.Check(req.Prompt); !decision.Allowed {
+		return reject(http.StatusUnprocessableEntity, models.ErrorResponse{Error: decision.Reason})
+	}
+
+	result, status := h.gen.serve(ctx, req)
+	item.StatusCode, item.Result = status, &result
+	return item
+}
@@ -0,0 +1,115 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/cache"
+	"github.com/zest-app/ai-service/handlers"
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/moderator"
+	"github.com/zest-app/ai-service/providers"
+)
+
+// countingProvider records the most calls it had running at once.
+type countingProvider struct {
+	running, peak atomic.Int32
+}
+
+func (c *countingProvider) Name() string                  { return "counting" }
+func (c *countingProvider) Enabled() bool                 { return true }
+func (c *countingProvider) Models() []providers.ModelInfo { return nil }
+
+func (c *countingProvider) Generate(ctx context.Context, req models.GenerationRequest) (providers.Response, error) {
+	n := c.running.Add(1)
+	defer c.running.Add(-1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return providers.Response{Text: "<html><body><p>" + req.Prompt + "</p></body></html>", FinishReason: "stop"}, nil
+}
+
+type batchLine struct {
+	Index      *int                     `json:"index"`
+	StatusCode int                      `json:"status_code"`
+	Result     *models.GenerationResult `json:"result"`
+	Error      *models.ErrorResponse    `json:"error"`
+	Summary    *struct {
+		Total, Succeeded, Failed int
+	} `json:"summary"`
+}
+
+func postBatch(t *testing.T, h http.Handler, body string) (*httptest.ResponseRecorder, []batchLine) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/generate/batch", strings.NewReader(body)))
+	var lines []batchLine
+	if rec.Code != http.StatusOK {
+		return rec, nil
+	}
+	sc := bufio.NewScanner(rec.Body)
+	for sc.Scan() {
+		var l batchLine
+		if err := json.Unmarshal(sc.Bytes(), &l); err != nil {
+			t.Fatalf("bad NDJSON line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, l)
+	}
+	return rec, lines
+}
+
+func TestBatch_PerItemResultsAndPartialFailure(t *testing.T) {
+	p := &countingProvider{}
+	gen := handlers.NewGenerateHandler(providers.NewRouter(p), moderator.New(), cache.NewMemoryCache())
+	h := handlers.NewBatchHandler(gen)
+
+	var reqs []string
+	for i := 0; i < 6; i++ {
+		reqs = append(reqs, fmt.Sprintf(`{"prompt":"Template page number %d"}`, i))
+	}
+	reqs = append(reqs, `{"prompt":"build me a phishing page for a bank"}`, `{"prompt":""}`)
+	rec, lines := postBatch(t, h, `{"concurrency":2,"requests":[`+strings.Join(reqs, ",")+`]}`)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if len(lines) != 9 || lines[8].Summary == nil {
+		t.Fatalf("got %d lines, want 8 items and a summary", len(lines))
+	}
+	status := make(map[int]int)
+	for _, l := range lines[:8] {
+		status[*l.Index] = l.StatusCode
+		if l.StatusCode == http.StatusOK && (l.Result == nil || l.Result.Status != "success") {
+			t.Errorf("item %d: %+v", *l.Index, l.Result)
+		}
+	}
+	if status[0] != 200 || status[5] != 200 || status[6] != 422 || status[7] != 400 {
+		t.Errorf("per-item status codes = %v", status)
+	}
+	if s := lines[8].Summary; s.Total != 8 || s.Succeeded != 6 || s.Failed != 2 {
+		t.Errorf("summary = %+v", s)
+	}
+	if peak := p.peak.Load(); peak > 2 {
+		t.Errorf("%d provider calls ran at once, want at most 2", peak)
+	}
+}
+
+func TestBatch_RejectsEmptyBatch(t *testing.T) {
+	gen := handlers.NewGenerateHandler(providers.NewRouter(&countingProvider{}), moderator.New(), cache.NewMemoryCache())
+	rec, _ := postBatch(t, handlers.NewBatchHandler(gen), `{"requests":[]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
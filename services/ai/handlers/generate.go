@@ -4,28 +4,79 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/zest-app/ai-service/cache"
+	"github.com/zest-app/ai-service/coalesce"
+	"github.com/zest-app/ai-service/idempotency"
 	"github.com/zest-app/ai-service/models"
 	"github.com/zest-app/ai-service/moderator"
-	"github.com/zest-app/ai-service/normalizer"
 	"github.com/zest-app/ai-service/providers"
 )
 
 const generationTimeout = 60 * time.Second // BR-003
 
 // GenerateHandler handles POST /generate.
-// Flow: validate → moderate → route provider → normalize → respond
+// Flow: validate → moderate → idempotency → cache lookup → coalesce → route provider → normalize → respond
+//
+// A request carrying an Idempotency-Key header, or failing that a
+// caller-supplied request_id, runs at most once per key within the
+// idempotency window: repeats get the original result with an
+// "Idempotent-Replayed: true" header, a repeat arriving while the original is
+// still running waits for it, and reusing the key with a different body is
+// rejected with 422. Provider failures are not remembered, so those may be
+// retried under the same key.
 type GenerateHandler struct {
 	router *providers.Router
 	mod    *moderator.Moderator
+	cache  cache.Cache
+	// inflight shares one provider call between identical requests that
+	// arrive while it is running, before its result reaches the cache.
+	inflight coalesce.Group[generation]
+
+	idem       idempotency.Store
+	idemWindow time.Duration
+}
+
+// idempotencyLockTTL bounds how long a key stays "in progress" if the
+// instance running it dies, so the key does not stay locked for the whole
+// window.
+const idempotencyLockTTL = 2 * generationTimeout
+
+// idempotencyPollInterval is how often a repeat checks whether the original
+// request has finished.
+const idempotencyPollInterval = 250 * time.Millisecond
+
+// generation is the outcome of a provider call shared by coalesced requests.
+type generation struct {
+	result models.GenerationResult
+	status int
 }
 
 // NewGenerateHandler creates a GenerateHandler.
-func NewGenerateHandler(router *providers.Router, mod *moderator.Moderator) *GenerateHandler {
-	return &GenerateHandler{router: router, mod: mod}
+func NewGenerateHandler(router *providers.Router, mod *moderator.Moderator, c cache.Cache) *GenerateHandler {
+	return &GenerateHandler{
+		router:     router,
+		mod:        mod,
+		cache:      c,
+		idem:       idempotency.NewMemoryStore(),
+		idemWindow: idempotency.DefaultWindow,
+	}
+}
+
+// WithIdempotency replaces the in-memory idempotency store, e.g. with a Redis
+// one shared by all instances, and sets how long outcomes are kept.
+// Non-positive windows keep the default.
+func (h *GenerateHandler) WithIdempotency(s idempotency.Store, window time.Duration) *GenerateHandler {
+	h.idem = s
+	if window > 0 {
+		h.idemWindow = window
+	}
+	return h
 }
 
 func (h *GenerateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -40,6 +91,10 @@ func (h *GenerateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkPreference(w, h.router, req) {
+		return
+	}
+
 	// BR-004: Moderation MUST run before LLM call
 	decision := h.mod.Check(req.Prompt)
 	if !decision.Allowed {
@@ -47,6 +102,14 @@ func (h *GenerateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The caller's request_id stands in for a missing header; the one
+	// assigned below never does, as it cannot repeat.
+	idemKey := r.Header.Get("Idempotency-Key")
+	if idemKey == "" {
+		idemKey = req.RequestID
+	}
+	fingerprint := idempotency.Fingerprint(req)
+
 	// Assign a request ID if caller didn't provide one
 	if req.RequestID == "" {
 		req.RequestID = uuid.New().String()
@@ -55,44 +118,249 @@ func (h *GenerateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		req.UserID = "anonymous"
 	}
 
-	// BR-003: 60-second timeout
-	ctx, cancel := context.WithTimeout(r.Context(), generationTimeout)
+	if idemKey != "" {
+		h.serveIdempotent(r.Context(), w, req, idempotency.Key(req.UserID, idemKey), fingerprint)
+		return
+	}
+	result, status := h.serve(r.Context(), req)
+	writeResult(w, status, result)
+}
+
+// serveIdempotent serves req at most once per key; see GenerateHandler.
+func (h *GenerateHandler) serveIdempotent(ctx context.Context, w http.ResponseWriter, req models.GenerationRequest, key, fingerprint string) {
+	ctx, cancel := context.WithTimeout(ctx, generationTimeout)
 	defer cancel()
 
-	start := time.Now()
-	raw, providerUsed, err := h.router.Route(ctx, req)
-	durationMs := time.Since(start).Milliseconds()
+	for {
+		claim := idempotency.Record{Fingerprint: fingerprint, State: idempotency.StateInProgress, Token: uuid.New().String()}
+		rec, created, err := h.idem.Begin(ctx, key, claim, idempotencyLockTTL)
+		if err != nil {
+			// As with the cache, an unavailable store never blocks generation.
+			log.Printf("[ai-service] idempotency begin %s: %v", key, err)
+			result, status := h.serve(ctx, req)
+			writeResult(w, status, result)
+			return
+		}
 
-	if err != nil {
-		result := models.GenerationResult{
-			GenerationID: uuid.New().String(),
-			Status:       "error",
-			ProviderUsed: providerUsed,
-			DurationMs:   durationMs,
-			Error:        err.Error(),
+		if created {
+			result, status := h.serve(ctx, req)
+			h.finishIdempotent(key, claim, result, status)
+			writeResult(w, status, result)
+			return
+		}
+
+		if rec.Fingerprint != fingerprint {
+			writeErrorResponse(w, http.StatusUnprocessableEntity, models.ErrorResponse{
+				Error: "idempotency key was already used with a different request body",
+				Code:  "idempotency_key_reused",
+			})
+			return
+		}
+
+		if rec.State == idempotency.StateInProgress {
+			var found bool
+			if rec, found = h.awaitIdempotent(ctx, key); !found {
+				if ctx.Err() != nil {
+					writeErrorResponse(w, http.StatusConflict, models.ErrorResponse{
+						Error: "a request with this idempotency key is still in progress",
+						Code:  "request_in_progress",
+					})
+					return
+				}
+				continue // the original failed and released the key; run it now
+			}
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadGateway)
-		json.NewEncoder(w).Encode(result)
+
+		w.Header().Set("Idempotent-Replayed", "true")
+		writeResult(w, rec.StatusCode, rec.Result)
 		return
 	}
+}
 
-	// Normalize raw LLM response into HTML + CSS
-	format := req.Preferences.OutputFormat
-	if format == "" {
-		format = "html_css"
+// finishIdempotent remembers a final outcome for the window, or releases the
+// key after a provider failure so the caller may retry.
+func (h *GenerateHandler) finishIdempotent(key string, claim idempotency.Record, result models.GenerationResult, status int) {
+	// The client may be gone; the outcome must still be recorded.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var err error
+	if status == http.StatusOK || status == http.StatusUnprocessableEntity {
+		err = h.idem.Complete(ctx, key, idempotency.Record{
+			Fingerprint: claim.Fingerprint,
+			State:       idempotency.StateCompleted,
+			StatusCode:  status,
+			Result:      result,
+		}, h.idemWindow)
+	} else {
+		err = h.idem.Release(ctx, key, claim.Token)
 	}
-	parsed := normalizer.Parse(raw, format)
+	if err != nil {
+		log.Printf("[ai-service] idempotency finish %s: %v", key, err)
+	}
+}
 
-	result := models.GenerationResult{
-		GenerationID: uuid.New().String(),
-		Status:       "success",
-		HTML:         parsed.HTML,
-		CSS:          parsed.CSS,
-		ProviderUsed: providerUsed,
-		DurationMs:   durationMs,
+// awaitIdempotent polls until the request holding key completes. It reports
+// false if the key was released or ctx ended first.
+func (h *GenerateHandler) awaitIdempotent(ctx context.Context, key string) (idempotency.Record, bool) {
+	ticker := time.NewTicker(idempotencyPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return idempotency.Record{}, false
+		case <-ticker.C:
+		}
+		rec, found, err := h.idem.Get(ctx, key)
+		if err != nil {
+			log.Printf("[ai-service] idempotency get %s: %v", key, err)
+			continue
+		}
+		if !found {
+			return rec, false
+		}
+		if rec.State == idempotency.StateCompleted {
+			return rec, true
+		}
 	}
+}
 
+// writeResult writes a GenerationResult with status.
+func writeResult(w http.ResponseWriter, status int, result models.GenerationResult) {
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(result)
 }
+
+// serve produces the result for a validated, moderated request: from the
+// cache, from an identical request in flight, or from a provider.
+func (h *GenerateHandler) serve(ctx context.Context, req models.GenerationRequest) (models.GenerationResult, int) {
+	// BR-003: 60-second timeout
+	ctx, cancel := context.WithTimeout(ctx, generationTimeout)
+	defer cancel()
+
+	start := time.Now()
+
+	// BR-007/BR-008: serve identical prompts in the same format from cache
+	key := cache.Key(req)
+	if cached, ok := lookupCache(ctx, h.cache, key, start); ok {
+		return cached, http.StatusOK
+	}
+
+	// Identical requests already being generated wait for that call instead
+	// of starting their own.
+	gen, shared, err := h.inflight.Do(ctx, coalesceKey(key, req), func(ctx context.Context) (generation, error) {
+		return h.generate(ctx, req, key), nil
+	})
+	result, status := gen.result, gen.status
+	if err != nil {
+		// This client gave up; the call carries on for any other waiters.
+		result, status = failureResult(err, providers.Response{}, 0)
+	}
+	result.GenerationID = uuid.New().String()
+	result.DurationMs = time.Since(start).Milliseconds()
+	if shared && err == nil {
+		log.Printf("[ai-service] request %s coalesced with an identical in-flight request", req.RequestID)
+		result.Coalesced = true
+		result.TokenCount, result.PromptTokens, result.CompletionTokens = 0, 0, 0
+	}
+	return result, status
+}
+
+// generate routes req to a provider and caches a successful result under key.
+// It runs once per group of coalesced requests, on a context that outlives
+// any single client.
+func (h *GenerateHandler) generate(ctx context.Context, req models.GenerationRequest, key string) generation {
+	// BR-003: 60-second timeout, counted from the first request of the group
+	ctx, cancel := context.WithTimeout(ctx, generationTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := h.router.Route(ctx, req)
+	durationMs := time.Since(start).Milliseconds()
+
+	if err != nil {
+		result, status := failureResult(err, resp, durationMs)
+		return generation{result: result, status: status}
+	}
+
+	// Normalize and sanitize raw LLM response into HTML + CSS
+	result := successResult(resp, req, durationMs)
+	result.GenerationID = uuid.New().String()
+	storeCache(ctx, h.cache, key, req, result)
+	return generation{result: result, status: http.StatusOK}
+}
+
+// coalesceKey identifies requests that can share one provider call: those
+// with the same cache key and the same fallback behaviour.
+func coalesceKey(cacheKey string, req models.GenerationRequest) string {
+	if req.StrictProvider {
+		return cacheKey + ":strict"
+	}
+	return cacheKey
+}
+
+// checkPreference rejects a request whose preferred provider or model is not
+// offered, writing a 400 that lists the valid choices. It reports whether
+// the request may proceed.
+func checkPreference(w http.ResponseWriter, router *providers.Router, req models.GenerationRequest) bool {
+	if e := preferenceError(router, req); e != nil {
+		writeErrorResponse(w, http.StatusBadRequest, *e)
+		return false
+	}
+	return true
+}
+
+// preferenceError returns the 400 body for a request whose preferred provider
+// or model is not offered, or nil if the request may proceed.
+func preferenceError(router *providers.Router, req models.GenerationRequest) *models.ErrorResponse {
+	err := router.CheckPreference(req)
+	if err == nil {
+		return nil
+	}
+	var perr *providers.PreferenceError
+	if errors.As(err, &perr) {
+		return &models.ErrorResponse{
+			Error:        perr.Message,
+			Code:         perr.Code,
+			ValidChoices: perr.Choices,
+		}
+	}
+	return &models.ErrorResponse{Error: err.Error()}
+}
+
+// lookupCache returns the cached result for key, stamped as a fresh cache hit
+// for a request that started at start. Cache failures are logged and treated
+// as a miss so an unavailable Redis never blocks generation.
+func lookupCache(ctx context.Context, c cache.Cache, key string, start time.Time) (models.GenerationResult, bool) {
+	result, ok, err := c.Get(ctx, key)
+	if err != nil {
+		log.Printf("[ai-service] cache get %s: %v", key, err)
+		return models.GenerationResult{}, false
+	}
+	if !ok {
+		return result, false
+	}
+	result.GenerationID = uuid.New().String()
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.CacheHit = true
+	// No provider call was made, so no tokens were spent on this request.
+	result.TokenCount, result.PromptTokens, result.CompletionTokens = 0, 0, 0
+	return result, true
+}
+
+// storeCache saves a successful result under key for cache.DefaultTTL. A
+// result from a fallback provider is not stored under a key pinned to another
+// provider or model, or a later strict request for the pinned one would be
+// served it.
+func storeCache(ctx context.Context, c cache.Cache, key string, req models.GenerationRequest, result models.GenerationResult) {
+	if req.PreferredProvider != "" && result.ProviderUsed != req.PreferredProvider {
+		return
+	}
+	if req.PreferredModel != "" && result.ModelUsed != req.PreferredModel {
+		return
+	}
+	if err := c.Set(ctx, key, result, cache.DefaultTTL); err != nil {
+		log.Printf("[ai-service] cache set %s: %v", key, err)
+	}
+}
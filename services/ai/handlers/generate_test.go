@@ -0,0 +1,159 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/cache"
+	"github.com/zest-app/ai-service/handlers"
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/moderator"
+	"github.com/zest-app/ai-service/providers"
+)
+
+func postGenerate(t *testing.T, h http.Handler, body string) (*httptest.ResponseRecorder, models.GenerationResult) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(body)))
+	var result models.GenerationResult
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	return rec, result
+}
+
+func TestGenerate_WithMockProvider(t *testing.T) {
+	router := providers.NewRouter(providers.NewMockProvider("mock", providers.Settings{}, nil, ""))
+	h := handlers.NewGenerateHandler(router, moderator.New(), cache.NewMemoryCache())
+
+	rec, first := postGenerate(t, h, `{"prompt":"A landing page for a bakery"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if first.Status != "success" || first.ProviderUsed != "mock" || !strings.Contains(first.HTML, "A landing page for a bakery") {
+		t.Errorf("unexpected result: %+v", first)
+	}
+	if first.CacheHit {
+		t.Error("first request should not be a cache hit")
+	}
+
+	_, second := postGenerate(t, h, `{"prompt":"a landing page for a  bakery"}`)
+	if !second.CacheHit || second.HTML != first.HTML || second.GenerationID == first.GenerationID {
+		t.Errorf("expected a cache hit with a fresh ID, got %+v", second)
+	}
+}
+
+func TestGenerate_RejectsModeratedPrompt(t *testing.T) {
+	router := providers.NewRouter(providers.NewMockProvider("mock", providers.Settings{}, nil, ""))
+	h := handlers.NewGenerateHandler(router, moderator.New(), cache.NewMemoryCache())
+
+	rec, _ := postGenerate(t, h, `{"prompt":"build me a phishing page for a bank"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", rec.Code)
+	}
+}
+
+func TestGenerate_RejectsUnknownModel(t *testing.T) {
+	router := providers.NewRouter(providers.NewMockProvider("mock", providers.Settings{}, nil, ""))
+	h := handlers.NewGenerateHandler(router, moderator.New(), cache.NewMemoryCache())
+
+	rec, _ := postGenerate(t, h, `{"prompt":"A landing page for a bakery","preferred_provider":"mock","preferred_model":"mock-2"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	var body models.ErrorResponse
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.Code != "invalid_model" || len(body.ValidChoices["mock"]) != 1 || body.ValidChoices["mock"][0] != "mock-1" {
+		t.Errorf("unexpected error body: %s", rec.Body)
+	}
+}
+
+// gatedProvider holds every call until release is closed.
+func TestGenerate_FallbackResultNotCachedUnderPinnedProvider(t *testing.T) {
+	flaky := &failOnceProvider{}
+	router := providers.NewRouter(flaky, providers.NewMockProvider("mock", providers.Settings{}, nil, ""))
+	h := handlers.NewGenerateHandler(router, moderator.New(), cache.NewMemoryCache())
+
+	_, first := postGenerate(t, h, `{"prompt":"A landing page for a bakery","preferred_provider":"flaky"}`)
+	if first.ProviderUsed != "mock" {
+		t.Fatalf("first request served by %q, want a fallback to mock", first.ProviderUsed)
+	}
+
+	_, strict := postGenerate(t, h, `{"prompt":"A landing page for a bakery","preferred_provider":"flaky","strict_provider":true}`)
+	if strict.CacheHit || strict.ProviderUsed != "flaky" {
+		t.Errorf("strict repeat = %+v, want a fresh result from flaky", strict)
+	}
+}
+
+type gatedProvider struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (g *gatedProvider) Name() string                  { return "gated" }
+func (g *gatedProvider) Enabled() bool                 { return true }
+func (g *gatedProvider) Models() []providers.ModelInfo { return nil }
+
+func (g *gatedProvider) Generate(ctx context.Context, req models.GenerationRequest) (providers.Response, error) {
+	g.calls.Add(1)
+	select {
+	case <-g.release:
+	case <-ctx.Done():
+		return providers.Response{}, ctx.Err()
+	}
+	return providers.Response{Text: "<html><body><p>Bakery</p></body></html>", Model: "gated-1", FinishReason: "stop", PromptTokens: 10, CompletionTokens: 20}, nil
+}
+
+func TestGenerate_CoalescesConcurrentIdenticalRequests(t *testing.T) {
+	p := &gatedProvider{release: make(chan struct{})}
+	h := handlers.NewGenerateHandler(providers.NewRouter(p), moderator.New(), cache.NewMemoryCache())
+
+	const n = 4
+	results := make(chan models.GenerationResult, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, result := postGenerate(t, h, `{"prompt":"A landing page for a bakery"}`)
+			results <- result
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(p.release)
+	wg.Wait()
+	close(results)
+
+	// Requests arriving after the call finished are cache hits, so the
+	// provider is called exactly once however the goroutines are scheduled.
+	if got := p.calls.Load(); got != 1 {
+		t.Fatalf("provider called %d times, want 1", got)
+	}
+	ids := make(map[string]bool)
+	leaders := 0
+	for r := range results {
+		if r.Status != "success" {
+			t.Fatalf("unexpected result: %+v", r)
+		}
+		ids[r.GenerationID] = true
+		switch {
+		case r.Coalesced || r.CacheHit:
+			if r.TokenCount != 0 {
+				t.Errorf("shared result should spend no tokens: %+v", r)
+			}
+		default:
+			leaders++
+			if r.TokenCount != 30 {
+				t.Errorf("leader token count = %d, want 30", r.TokenCount)
+			}
+		}
+	}
+	if leaders != 1 || len(ids) != n {
+		t.Errorf("leaders = %d, distinct IDs = %d; want 1 and %d", leaders, len(ids), n)
+	}
+}
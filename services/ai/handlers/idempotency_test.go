@@ -0,0 +1,133 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/cache"
+	"github.com/zest-app/ai-service/handlers"
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/moderator"
+	"github.com/zest-app/ai-service/providers"
+)
+
+func postWithKey(t *testing.T, h http.Handler, key, body string) (*httptest.ResponseRecorder, models.GenerationResult) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	h.ServeHTTP(rec, req)
+	var result models.GenerationResult
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	return rec, result
+}
+
+func TestGenerate_IdempotencyKeyReplaysResult(t *testing.T) {
+	p := &countingProvider{}
+	h := handlers.NewGenerateHandler(providers.NewRouter(p), moderator.New(), cache.NewMemoryCache())
+	body := `{"prompt":"A landing page for a bakery"}`
+
+	_, first := postWithKey(t, h, "retry-1", body)
+	rec, again := postWithKey(t, h, "retry-1", body)
+	if rec.Code != http.StatusOK || again.GenerationID != first.GenerationID || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("repeat = %d %+v, want the original result replayed", rec.Code, again)
+	}
+
+	rec, _ = postWithKey(t, h, "retry-1", `{"prompt":"A landing page for a florist"}`)
+	var body422 models.ErrorResponse
+	json.Unmarshal(rec.Body.Bytes(), &body422)
+	if rec.Code != http.StatusUnprocessableEntity || body422.Code != "idempotency_key_reused" {
+		t.Errorf("reused key with another body = %d %s", rec.Code, rec.Body)
+	}
+
+	// Each call from the web app carries a fresh request_id; a retry under
+	// the same key is still the same request.
+	rec, again = postWithKey(t, h, "retry-1", `{"request_id":"req_other","prompt":"A landing page for a bakery"}`)
+	if rec.Code != http.StatusOK || again.GenerationID != first.GenerationID {
+		t.Errorf("retry with a new request_id = %d %+v, want a replay", rec.Code, again)
+	}
+
+	// Without the header, a caller-supplied request_id is the key.
+	withID := `{"request_id":"req-42","prompt":"A portfolio page"}`
+	_, a := postWithKey(t, h, "", withID)
+	rec, b := postWithKey(t, h, "", withID)
+	if a.GenerationID != b.GenerationID || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("repeat with the same request_id got a new generation: %s vs %s", a.GenerationID, b.GenerationID)
+	}
+
+	// Requests without either are never replayed.
+	_, c := postWithKey(t, h, "", `{"prompt":"A portfolio page"}`)
+	rec, d := postWithKey(t, h, "", `{"prompt":"A portfolio page"}`)
+	if c.GenerationID == d.GenerationID || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("request without a key was replayed: %s", d.GenerationID)
+	}
+}
+
+func TestGenerate_IdempotentRepeatWaitsForInProgress(t *testing.T) {
+	p := &gatedProvider{release: make(chan struct{})}
+	h := handlers.NewGenerateHandler(providers.NewRouter(p), moderator.New(), cache.NewMemoryCache())
+	body := `{"prompt":"A landing page for a bakery"}`
+
+	results := make([]models.GenerationResult, 2)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, results[0] = postWithKey(t, h, "slow", body)
+	}()
+	for p.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, results[1] = postWithKey(t, h, "slow", body)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(p.release)
+	wg.Wait()
+
+	if p.calls.Load() != 1 || results[0].GenerationID == "" || results[1].GenerationID != results[0].GenerationID {
+		t.Errorf("calls = %d, results = %+v; want one call and the same result twice", p.calls.Load(), results)
+	}
+}
+
+// failOnceProvider fails its first call with a server error.
+type failOnceProvider struct {
+	calls atomic.Int32
+}
+
+func (f *failOnceProvider) Name() string                  { return "flaky" }
+func (f *failOnceProvider) Enabled() bool                 { return true }
+func (f *failOnceProvider) Models() []providers.ModelInfo { return nil }
+
+func (f *failOnceProvider) Generate(ctx context.Context, req models.GenerationRequest) (providers.Response, error) {
+	if f.calls.Add(1) == 1 {
+		return providers.Response{}, errors.New("upstream exploded")
+	}
+	return providers.Response{Text: "<html><body><p>ok</p></body></html>", FinishReason: "stop"}, nil
+}
+
+func TestGenerate_IdempotencyKeyReleasedAfterFailure(t *testing.T) {
+	p := &failOnceProvider{}
+	h := handlers.NewGenerateHandler(providers.NewRouter(p), moderator.New(), cache.NewMemoryCache())
+	body := `{"prompt":"A landing page for a bakery"}`
+
+	if rec, _ := postWithKey(t, h, "k", body); rec.Code != http.StatusBadGateway {
+		t.Fatalf("first call = %d, want 502", rec.Code)
+	}
+	rec, result := postWithKey(t, h, "k", body)
+	if rec.Code != http.StatusOK || result.Status != "success" || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after failure = %d %+v, want a fresh generation", rec.Code, result)
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/zest-app/ai-service/cache"
+	"github.com/zest-app/ai-service/jobs"
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/moderator"
+	"github.com/zest-app/ai-service/providers"
+)
+
+// JobsHandler serves the asynchronous generation API:
+//
+//	POST   /jobs       queue a GenerationRequest; 202 with the Job
+//	GET    /jobs/{id}  the Job with its status, progress and result
+//	DELETE /jobs/{id}  cancel a queued or running job; 409 once finished
+//
+// A request with a callback_url is also POSTed its result when the job
+// succeeds or fails (events "job.succeeded" and "job.failed").
+//
+// Validation and moderation run before the job is queued, so a rejected
+// prompt fails the POST exactly as it would /generate.
+type JobsHandler struct {
+	router *providers.Router
+	mod    *moderator.Moderator
+	jobs   *jobs.Manager
+}
+
+// NewJobsHandler creates a JobsHandler.
+func NewJobsHandler(router *providers.Router, mod *moderator.Moderator, m *jobs.Manager) *JobsHandler {
+	return &JobsHandler{router: router, mod: mod, jobs: m}
+}
+
+// Create handles POST /jobs.
+func (h *JobsHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.GenerationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if req.Prompt == "" {
+		writeError(w, http.StatusBadRequest, "prompt is required")
+		return
+	}
+
+	if !checkPreference(w, h.router, req) {
+		return
+	}
+
+	// BR-004: Moderation MUST run before LLM call
+	decision := h.mod.Check(req.Prompt)
+	if !decision.Allowed {
+		writeError(w, http.StatusUnprocessableEntity, decision.Reason)
+		return
+	}
+
+	if req.RequestID == "" {
+		req.RequestID = uuid.New().String()
+	}
+	if req.UserID == "" {
+		req.UserID = "anonymous"
+	}
+
+	job, err := h.jobs.Submit(r.Context(), req)
+	if errors.Is(err, jobs.ErrCallback) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, jobs.ErrQueueFull) {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJob(w, http.StatusAccepted, job)
+}
+
+// Get handles GET /jobs/{id}.
+func (h *JobsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJob(w, http.StatusOK, job)
+}
+
+// Delete handles DELETE /jobs/{id}.
+func (h *JobsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Cancel(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJob(w, http.StatusOK, job)
+}
+
+func writeJob(w http.ResponseWriter, status int, job jobs.Job) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(job)
+}
+
+func writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, jobs.ErrFinished):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// NewJobRunner returns the jobs.RunFunc used by the worker pool. It follows
+// the /generate/stream flow, counting streamed characters as progress.
+func NewJobRunner(router *providers.Router, c cache.Cache) jobs.RunFunc {
+	return func(ctx context.Context, req models.GenerationRequest, progress func(jobs.Progress)) models.GenerationResult {
+		// BR-003: 60-second timeout, counted from when a worker picks the job up
+		ctx, cancel := context.WithTimeout(ctx, generationTimeout)
+		defer cancel()
+
+		start := time.Now()
+
+		key := cache.Key(req)
+		if cached, ok := lookupCache(ctx, c, key, start); ok {
+			return cached
+		}
+
+		current, received := "", 0
+		onChunk := func(provider, text string) error {
+			if provider != current {
+				current, received = provider, 0
+			}
+			received += utf8.RuneCountInString(text)
+			progress(jobs.Progress{Stage: "generating", Provider: provider, ReceivedChars: received})
+			return nil
+		}
+
+		resp, err := router.RouteStream(ctx, req, onChunk)
+		durationMs := time.Since(start).Milliseconds()
+
+		if err != nil {
+			result, _ := failureResult(err, resp, durationMs)
+			return result
+		}
+
+		result := successResult(resp, req, durationMs)
+		result.GenerationID = uuid.New().String()
+		storeCache(ctx, c, key, req, result)
+		return result
+	}
+}
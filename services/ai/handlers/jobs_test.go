@@ -0,0 +1,77 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/zest-app/ai-service/cache"
+	"github.com/zest-app/ai-service/handlers"
+	"github.com/zest-app/ai-service/jobs"
+	"github.com/zest-app/ai-service/moderator"
+	"github.com/zest-app/ai-service/providers"
+)
+
+func newJobsServer(t *testing.T) http.Handler {
+	t.Helper()
+	router := providers.NewRouter(providers.NewMockProvider("mock", providers.Settings{}, nil, ""))
+	m := jobs.NewManager(jobs.NewMemoryStore(), jobs.NewMemoryQueue(8), handlers.NewJobRunner(router, cache.NewMemoryCache()))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	m.Start(ctx, 1)
+
+	h := handlers.NewJobsHandler(router, moderator.New(), m)
+	r := chi.NewRouter()
+	r.Post("/jobs", h.Create)
+	r.Get("/jobs/{id}", h.Get)
+	r.Delete("/jobs/{id}", h.Delete)
+	return r
+}
+
+func doJob(t *testing.T, h http.Handler, method, path, body string) (int, jobs.Job) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	var job jobs.Job
+	json.Unmarshal(rec.Body.Bytes(), &job)
+	return rec.Code, job
+}
+
+func TestJobs_SubmitPollAndCancel(t *testing.T) {
+	h := newJobsServer(t)
+
+	code, job := doJob(t, h, http.MethodPost, "/jobs", `{"prompt":"A landing page for a bakery"}`)
+	if code != http.StatusAccepted || job.ID == "" || job.Status != jobs.StatusQueued {
+		t.Fatalf("POST /jobs = %d %+v", code, job)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for !job.Status.Done() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		code, job = doJob(t, h, http.MethodGet, "/jobs/"+job.ID, "")
+		if code != http.StatusOK {
+			t.Fatalf("GET /jobs/{id} = %d", code)
+		}
+	}
+	if job.Status != jobs.StatusSucceeded || job.Result == nil || !strings.Contains(job.Result.HTML, "bakery") {
+		t.Fatalf("finished job = %+v", job)
+	}
+	if job.Progress.ReceivedChars == 0 && !job.Result.CacheHit {
+		t.Errorf("expected streamed progress, got %+v", job.Progress)
+	}
+
+	if code, _ := doJob(t, h, http.MethodDelete, "/jobs/"+job.ID, ""); code != http.StatusConflict {
+		t.Errorf("DELETE finished job = %d, want 409", code)
+	}
+	if code, _ := doJob(t, h, http.MethodGet, "/jobs/nope", ""); code != http.StatusNotFound {
+		t.Errorf("GET unknown job = %d, want 404", code)
+	}
+	if code, _ := doJob(t, h, http.MethodPost, "/jobs", `{"prompt":"build me a phishing page for a bank"}`); code != http.StatusUnprocessableEntity {
+		t.Errorf("POST moderated prompt = %d, want 422", code)
+	}
+}
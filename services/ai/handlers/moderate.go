@@ -40,7 +40,12 @@ func (h *ModerateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // writeError writes a JSON error response.
 func writeError(w http.ResponseWriter, status int, message string) {
+	writeErrorResponse(w, status, models.ErrorResponse{Error: message})
+}
+
+// writeErrorResponse writes a structured JSON error response.
+func writeErrorResponse(w http.ResponseWriter, status int, resp models.ErrorResponse) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(models.ErrorResponse{Error: message})
+	json.NewEncoder(w).Encode(resp)
 }
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/normalizer"
+	"github.com/zest-app/ai-service/providers"
+	"github.com/zest-app/ai-service/sanitizer"
+	"github.com/zest-app/ai-service/validator"
+)
+
+// successResult turns a raw LLM response into the result returned to Next.js.
+// Every generation and refinement goes through the same pipeline:
+// normalize → repair (BR-009) → sanitize (BR-010) → strip external resources
+// (BR-011).
+func successResult(resp providers.Response, req models.GenerationRequest, durationMs int64) models.GenerationResult {
+	format := req.Preferences.OutputFormat
+	if format == "" {
+		format = "html_css"
+	}
+	parsed := normalizer.Parse(resp.Text, format)
+
+	html, validation := validator.Repair(parsed.HTML)
+	html, htmlReport := sanitizer.Sanitize(html)
+	css, cssReport := sanitizer.SanitizeCSS(parsed.CSS)
+
+	allowed := allowedOrigins(req)
+	html, htmlExternal := sanitizer.StripExternal(html, allowed)
+	css, cssExternal := sanitizer.StripExternalCSS(css, allowed)
+
+	result := models.GenerationResult{
+		Status:           "success",
+		HTML:             html,
+		CSS:              css,
+		ProviderUsed:     resp.Provider,
+		ModelUsed:        resp.Model,
+		DurationMs:       durationMs,
+		TokenCount:       resp.TotalTokens(),
+		PromptTokens:     resp.PromptTokens,
+		CompletionTokens: resp.CompletionTokens,
+		FinishReason:     resp.FinishReason,
+		Validation: &models.ValidationReport{
+			Fixed:     validation.Fixed,
+			Truncated: validation.Truncated,
+		},
+	}
+	removed := append(htmlReport.Removed, cssReport.Removed...)
+	external := append(htmlExternal.Removed, cssExternal.Removed...)
+	if len(removed) > 0 || len(external) > 0 {
+		result.Sanitization = &models.SanitizationReport{Removed: removed, External: external}
+	}
+	return result
+}
+
+// failureResult builds the result for a failed Route call and the HTTP status
+// to send it with. Provider safety blocks are reported as "moderated" with 422,
+// like our own moderation; everything else is a 502 "error".
+func failureResult(err error, resp providers.Response, durationMs int64) (models.GenerationResult, int) {
+	result := models.GenerationResult{
+		GenerationID: uuid.New().String(),
+		Status:       "error",
+		ProviderUsed: resp.Provider,
+		DurationMs:   durationMs,
+		FinishReason: resp.FinishReason,
+		Error:        err.Error(),
+	}
+	if errors.Is(err, providers.ErrContentFiltered) {
+		result.Status = "moderated"
+		return result, http.StatusUnprocessableEntity
+	}
+	return result, http.StatusBadGateway
+}
+
+// allowedOrigins returns the origins external resources may load from
+// (BR-011): the caller's, plus the Tailwind CDN for tailwind output.
+func allowedOrigins(req models.GenerationRequest) []string {
+	allowed := req.Preferences.AllowedOrigins
+	if req.Preferences.OutputFormat == "tailwind" {
+		allowed = append(allowed[:len(allowed):len(allowed)], sanitizer.TailwindCDN)
+	}
+	return allowed
+}
+
+// previewHTML cleans partial output for a streamed preview with the same
+// rules as the final result (BR-010, BR-011), so no chunk carries markup
+// that successResult would strip.
+func previewHTML(text string, req models.GenerationRequest) string {
+	html, _ := sanitizer.SanitizePartial(text)
+	html, _ = sanitizer.StripExternal(html, allowedOrigins(req))
+	return html
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/google/uuid"
 	"github.com/zest-app/ai-service/models"
 	"github.com/zest-app/ai-service/moderator"
-	"github.com/zest-app/ai-service/normalizer"
 	"github.com/zest-app/ai-service/prompts"
 	"github.com/zest-app/ai-service/providers"
 )
@@ -39,6 +38,10 @@ func (h *RefineHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkPreference(w, h.router, req) {
+		return
+	}
+
 	// BR-004: moderation before every LLM call, including refinements
 	decision := h.mod.Check(req.Prompt)
 	if !decision.Allowed {
@@ -70,37 +73,19 @@ func (h *RefineHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	start := time.Now()
-	raw, providerUsed, err := h.router.Route(ctx, req)
+	resp, err := h.router.Route(ctx, req)
 	durationMs := time.Since(start).Milliseconds()
 
 	if err != nil {
-		result := models.GenerationResult{
-			GenerationID: uuid.New().String(),
-			Status:       "error",
-			ProviderUsed: providerUsed,
-			DurationMs:   durationMs,
-			Error:        err.Error(),
-		}
+		result, status := failureResult(err, resp, durationMs)
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadGateway)
+		w.WriteHeader(status)
 		json.NewEncoder(w).Encode(result)
 		return
 	}
 
-	format := req.Preferences.OutputFormat
-	if format == "" {
-		format = "html_css"
-	}
-	parsed := normalizer.Parse(raw, format)
-
-	result := models.GenerationResult{
-		GenerationID: uuid.New().String(),
-		Status:       "success",
-		HTML:         parsed.HTML,
-		CSS:          parsed.CSS,
-		ProviderUsed: providerUsed,
-		DurationMs:   durationMs,
-	}
+	result := successResult(resp, req, durationMs)
+	result.GenerationID = uuid.New().String()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
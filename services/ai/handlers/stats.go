@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/zest-app/ai-service/providers"
+)
+
+// StatsHandler serves GET /providers/stats — the observed success rate and
+// latency per provider and model, and the routing strategy using them.
+type StatsHandler struct {
+	router *providers.Router
+}
+
+// NewStatsHandler creates a StatsHandler.
+func NewStatsHandler(router *providers.Router) *StatsHandler {
+	return &StatsHandler{router: router}
+}
+
+func (h *StatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.router.Stats())
+}
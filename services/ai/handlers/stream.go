@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zest-app/ai-service/cache"
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/moderator"
+	"github.com/zest-app/ai-service/providers"
+)
+
+// StreamHandler handles POST /generate/stream.
+// It runs the same flow as GenerateHandler but responds with Server-Sent
+// Events so the caller can render the page while the LLM is still writing it:
+//
+//	event: chunk   data: {"provider":"gemini","html":"<html>..."}
+//	event: reset   data: {"provider":"glm"}      (previous chunks are stale)
+//	event: result  data: GenerationResult         (final, normalized)
+//	event: error   data: GenerationResult         (status "error" or "moderated")
+//
+// Each chunk carries the whole page so far, sanitized like the final result
+// (BR-010, BR-011), and replaces the previous one. Raw LLM output never
+// reaches the client.
+//
+// Validation and moderation failures are reported as plain JSON errors before
+// the event stream starts. A cache hit produces a single "result" event.
+type StreamHandler struct {
+	router *providers.Router
+	mod    *moderator.Moderator
+	cache  cache.Cache
+}
+
+// NewStreamHandler creates a StreamHandler.
+func NewStreamHandler(router *providers.Router, mod *moderator.Moderator, c cache.Cache) *StreamHandler {
+	return &StreamHandler{router: router, mod: mod, cache: c}
+}
+
+// streamChunk is the payload of "chunk" and "reset" events.
+type streamChunk struct {
+	Provider string `json:"provider"`
+	HTML     string `json:"html,omitempty"`
+}
+
+func (h *StreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req models.GenerationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if req.Prompt == "" {
+		writeError(w, http.StatusBadRequest, "prompt is required")
+		return
+	}
+
+	if !checkPreference(w, h.router, req) {
+		return
+	}
+
+	// BR-004: Moderation MUST run before LLM call
+	decision := h.mod.Check(req.Prompt)
+	if !decision.Allowed {
+		writeError(w, http.StatusUnprocessableEntity, decision.Reason)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	if req.RequestID == "" {
+		req.RequestID = uuid.New().String()
+	}
+	if req.UserID == "" {
+		req.UserID = "anonymous"
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// BR-003: 60-second timeout
+	ctx, cancel := context.WithTimeout(r.Context(), generationTimeout)
+	defer cancel()
+
+	start := time.Now()
+
+	key := cache.Key(req)
+	if cached, ok := lookupCache(ctx, h.cache, key, start); ok {
+		writeEvent(w, "result", cached)
+		flusher.Flush()
+		return
+	}
+
+	current := ""
+	var received strings.Builder
+	onChunk := func(provider, text string) error {
+		if current != "" && provider != current {
+			if err := writeEvent(w, "reset", streamChunk{Provider: provider}); err != nil {
+				return err
+			}
+			received.Reset()
+		}
+		current = provider
+		received.WriteString(text)
+		if err := writeEvent(w, "chunk", streamChunk{Provider: provider, HTML: previewHTML(received.String(), req)}); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	resp, err := h.router.RouteStream(ctx, req, onChunk)
+	durationMs := time.Since(start).Milliseconds()
+
+	if err != nil {
+		result, _ := failureResult(err, resp, durationMs)
+		writeEvent(w, "error", result)
+		flusher.Flush()
+		return
+	}
+
+	result := successResult(resp, req, durationMs)
+	result.GenerationID = uuid.New().String()
+	storeCache(ctx, h.cache, key, req, result)
+
+	writeEvent(w, "result", result)
+	flusher.Flush()
+}
+
+// writeEvent writes a single Server-Sent Event with a JSON data payload.
+func writeEvent(w http.ResponseWriter, event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
@@ -0,0 +1,53 @@
+package handlers_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zest-app/ai-service/cache"
+	"github.com/zest-app/ai-service/handlers"
+	"github.com/zest-app/ai-service/moderator"
+	"github.com/zest-app/ai-service/providers"
+)
+
+func TestStream_ChunksAreSanitized(t *testing.T) {
+	page := `<html><head><script src="https://evil.example/x.js"></script></head><body>` +
+		`<h1 onclick="steal()">Bakery</h1><a href="javascript:alert(1)">menu</a>` +
+		`<img src="https://tracker.example/p.gif"><script>alert(document.cookie)</script><p>Fresh bread daily</p></body></html>`
+	router := providers.NewRouter(providers.NewMockProvider("mock", providers.Settings{}, nil, page))
+	h := handlers.NewStreamHandler(router, moderator.New(), cache.NewMemoryCache())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/generate/stream", strings.NewReader(`{"prompt":"A landing page for a bakery"}`)))
+
+	var chunks []string
+	event := ""
+	sc := bufio.NewScanner(rec.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+		} else if data, ok := strings.CutPrefix(line, "data: "); ok && event == "chunk" {
+			var c struct{ HTML string }
+			json.Unmarshal([]byte(data), &c)
+			chunks = append(chunks, c.HTML)
+		}
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want the page streamed in several", len(chunks))
+	}
+	for i, c := range chunks {
+		for _, bad := range []string{"<script", "onclick", "javascript:", "evil.example", "tracker.example"} {
+			if strings.Contains(c, bad) {
+				t.Errorf("chunk %d contains %q: %s", i, bad, c)
+			}
+		}
+	}
+	if last := chunks[len(chunks)-1]; !strings.Contains(last, "Fresh bread daily") {
+		t.Errorf("last chunk lost the page content: %s", last)
+	}
+}
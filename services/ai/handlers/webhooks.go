@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/zest-app/ai-service/webhook"
+)
+
+// DeliveriesHandler serves GET /webhooks/deliveries — the recent callback
+// deliveries with every attempt, newest first. ?job_id= narrows it to one job.
+type DeliveriesHandler struct {
+	notifier *webhook.Notifier
+}
+
+// NewDeliveriesHandler creates a DeliveriesHandler.
+func NewDeliveriesHandler(n *webhook.Notifier) *DeliveriesHandler {
+	return &DeliveriesHandler{notifier: n}
+}
+
+func (h *DeliveriesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	deliveries := []webhook.Delivery{}
+	if h.notifier != nil {
+		jobID := r.URL.Query().Get("job_id")
+		for _, d := range h.notifier.Deliveries() {
+			if jobID == "" || d.JobID == jobID {
+				deliveries = append(deliveries, d)
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]webhook.Delivery{"deliveries": deliveries})
+}
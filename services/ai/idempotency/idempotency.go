@@ -0,0 +1,73 @@
+// Package idempotency remembers the outcome of a request by its idempotency
+// key, so a retried POST /generate returns the original GenerationResult
+// instead of paying for a second generation.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+)
+
+// DefaultWindow is how long a completed outcome is kept for replay.
+const DefaultWindow = 24 * time.Hour
+
+// keyPrefix follows the gen_cache:{hash} naming of the result cache.
+const keyPrefix = "gen_idem:"
+
+// Record states.
+const (
+	StateInProgress = "in_progress"
+	StateCompleted  = "completed"
+)
+
+// Record is what is stored under an idempotency key.
+type Record struct {
+	// Fingerprint identifies the request body the key was first used with.
+	Fingerprint string `json:"fingerprint"`
+	State       string `json:"state"`
+	// Token identifies the request holding an in-progress claim, so only
+	// that request can release it.
+	Token      string                  `json:"token,omitempty"`
+	StatusCode int                     `json:"status_code,omitempty"`
+	Result     models.GenerationResult `json:"result"`
+}
+
+// Store keeps Records by key.
+type Store interface {
+	// Begin stores rec under key for ttl unless the key is already taken.
+	// It returns the stored record and whether rec was stored.
+	Begin(ctx context.Context, key string, rec Record, ttl time.Duration) (Record, bool, error)
+	// Complete replaces the record under key for ttl.
+	Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error
+	// Get returns the record under key; the boolean is false if there is none.
+	Get(ctx context.Context, key string) (Record, bool, error)
+	// Release forgets key so the request can be tried again, provided it is
+	// still held by the claim with token. A claim that expired and was taken
+	// by another request is left alone.
+	Release(ctx context.Context, key, token string) error
+}
+
+// Key scopes a caller-supplied idempotency key to the user, so two users can
+// never collide.
+func Key(userID, idempotencyKey string) string {
+	if userID == "" {
+		userID = "anonymous"
+	}
+	return keyPrefix + userID + ":" + idempotencyKey
+}
+
+// Fingerprint hashes a request so reuse of its key with a different body can
+// be detected. The request is hashed in its decoded form, so field order and
+// whitespace in the original JSON do not matter, and only fields that affect
+// the output count: a retry may carry a fresh request_id.
+func Fingerprint(req models.GenerationRequest) string {
+	req.RequestID, req.UserID, req.CallbackURL = "", "", ""
+	b, _ := json.Marshal(req)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,52 @@
+package idempotency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/idempotency"
+	"github.com/zest-app/ai-service/models"
+)
+
+func TestMemoryStore_BeginClaimsOnce(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := idempotency.NewMemoryStore().WithClock(func() time.Time { return now })
+	key := idempotency.Key("user-1", "abc")
+	pending := idempotency.Record{Fingerprint: "f1", State: idempotency.StateInProgress, Token: "t1"}
+
+	if _, created, _ := s.Begin(ctx, key, pending, time.Minute); !created {
+		t.Fatal("first Begin should claim the key")
+	}
+	rec, created, _ := s.Begin(ctx, key, idempotency.Record{Fingerprint: "f2"}, time.Minute)
+	if created || rec.Fingerprint != "f1" || rec.State != idempotency.StateInProgress {
+		t.Fatalf("second Begin = %+v, %v; want the existing claim", rec, created)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, created, _ := s.Begin(ctx, key, pending, time.Minute); !created {
+		t.Error("an expired claim should be replaceable")
+	}
+	if err := s.Release(ctx, key, "someone-else"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := s.Get(ctx, key); !found {
+		t.Error("Release with another token removed the claim")
+	}
+	if idempotency.Key("user-2", "abc") == key {
+		t.Error("keys must be scoped per user")
+	}
+}
+
+func TestFingerprint_IgnoresRequestIdentity(t *testing.T) {
+	a := models.GenerationRequest{RequestID: "req_1", Prompt: "bakery", PreferredProvider: "gemini"}
+	b := models.GenerationRequest{RequestID: "req_2", UserID: "u1", PreferredProvider: "gemini", Prompt: "bakery"}
+	if idempotency.Fingerprint(a) != idempotency.Fingerprint(b) {
+		t.Error("retries with a new request_id must share a fingerprint")
+	}
+	b.Prompt = "florist"
+	if idempotency.Fingerprint(a) == idempotency.Fingerprint(b) {
+		t.Error("different prompts must not share a fingerprint")
+	}
+}
@@ -0,0 +1,46 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/zest-app/ai-service/ttlmap"
+)
+
+// MemoryStore is an in-process Store used in tests and when REDIS_URL is not
+// configured. Expired records are swept periodically.
+type MemoryStore struct {
+	records *ttlmap.Map[Record]
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: ttlmap.New[Record]()}
+}
+
+// WithClock replaces the time source, allowing tests to expire records
+// without sleeping.
+func (s *MemoryStore) WithClock(now func() time.Time) *MemoryStore {
+	s.records.WithClock(now)
+	return s
+}
+
+func (s *MemoryStore) Begin(ctx context.Context, key string, rec Record, ttl time.Duration) (Record, bool, error) {
+	stored, created := s.records.SetNX(key, rec, ttl)
+	return stored, created, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	s.records.Set(key, rec, ttl)
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Record, bool, error) {
+	rec, ok := s.records.Get(key)
+	return rec, ok, nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key, token string) error {
+	s.records.DeleteFunc(key, func(rec Record) bool { return rec.Token == token })
+	return nil
+}
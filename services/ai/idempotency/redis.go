@@ -0,0 +1,105 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore stores records as JSON strings in Redis, so every instance sees
+// the same keys. Begin relies on SET NX to claim a key atomically, and
+// Release on WATCH to delete only its own claim.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a RedisStore from a redis:// URL (e.g. REDIS_URL).
+func NewRedisStore(url string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("idempotency: parse redis url: %w", err)
+	}
+	return &RedisStore{client: redis.NewClient(opts)}, nil
+}
+
+func (s *RedisStore) Begin(ctx context.Context, key string, rec Record, ttl time.Duration) (Record, bool, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("idempotency: encode record: %w", err)
+	}
+	ok, err := s.client.SetNX(ctx, key, b, ttl).Result()
+	if err != nil {
+		return Record{}, false, fmt.Errorf("idempotency: redis setnx: %w", err)
+	}
+	if ok {
+		return rec, true, nil
+	}
+	existing, found, err := s.Get(ctx, key)
+	if err != nil {
+		return Record{}, false, err
+	}
+	if !found {
+		// Expired between the two calls; try once more.
+		return s.Begin(ctx, key, rec, ttl)
+	}
+	return existing, false, nil
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("idempotency: encode record: %w", err)
+	}
+	if err := s.client.Set(ctx, key, b, ttl).Err(); err != nil {
+		return fmt.Errorf("idempotency: redis set: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (Record, bool, error) {
+	var rec Record
+
+	b, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return rec, false, nil
+	}
+	if err != nil {
+		return rec, false, fmt.Errorf("idempotency: redis get: %w", err)
+	}
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return rec, false, fmt.Errorf("idempotency: decode record: %w", err)
+	}
+	return rec, true, nil
+}
+
+func (s *RedisStore) Release(ctx context.Context, key, token string) error {
+	err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+		b, err := tx.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var rec Record
+		if err := json.Unmarshal(b, &rec); err != nil || rec.Token != token {
+			return nil // not our claim
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			return nil
+		})
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil // the key changed hands meanwhile, so it is no longer ours
+	}
+	if err != nil {
+		return fmt.Errorf("idempotency: redis release: %w", err)
+	}
+	return nil
+}
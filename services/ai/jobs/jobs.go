@@ -0,0 +1,89 @@
+// Package jobs runs generations asynchronously. POST /jobs queues a request
+// and returns at once; a pool of workers takes jobs off a Queue and records
+// their progress and result in a Store, where GET /jobs/{id} reads them until
+// the retention period ends.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+)
+
+// DefaultRetention is how long a job and its result are kept after its last
+// update when JOB_RETENTION is unset.
+const DefaultRetention = 24 * time.Hour
+
+// Status is the lifecycle state of a job.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Done reports whether s is a final state.
+func (s Status) Done() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// Progress describes how far a running job has got. Stage is "queued",
+// "generating" or "done"; ReceivedChars counts streamed output so far and
+// starts over when the router falls back to another provider.
+type Progress struct {
+	Stage         string `json:"stage"`
+	Provider      string `json:"provider,omitempty"`
+	ReceivedChars int    `json:"received_chars"`
+}
+
+// Job is a queued generation and, once finished, its result.
+type Job struct {
+	ID       string                   `json:"id"`
+	Status   Status                   `json:"status"`
+	Progress Progress                 `json:"progress"`
+	Request  models.GenerationRequest `json:"request"`
+	// Result is set when the job succeeded, and on failure when the
+	// generation got as far as producing a result (e.g. "moderated").
+	Result     *models.GenerationResult `json:"result,omitempty"`
+	Error      string                   `json:"error,omitempty"`
+	CreatedAt  time.Time                `json:"created_at"`
+	StartedAt  *time.Time               `json:"started_at,omitempty"`
+	FinishedAt *time.Time               `json:"finished_at,omitempty"`
+}
+
+var (
+	// ErrNotFound is returned for an unknown or expired job ID.
+	ErrNotFound = errors.New("jobs: job not found")
+	// ErrQueueFull is returned when the queue cannot accept another job.
+	ErrQueueFull = errors.New("jobs: queue is full")
+	// ErrFinished is returned when canceling a job that already ended.
+	ErrFinished = errors.New("jobs: job already finished")
+	// ErrCallback wraps the reason a request's callback_url was rejected.
+	ErrCallback = errors.New("jobs: callback_url rejected")
+)
+
+// Store keeps jobs by ID.
+type Store interface {
+	// Get returns the job with id, or ErrNotFound.
+	Get(ctx context.Context, id string) (Job, error)
+	// Put saves job, replacing any previous version, for ttl.
+	Put(ctx context.Context, job Job, ttl time.Duration) error
+	// Update applies fn to the job with id and, if fn returns true, saves the
+	// result for ttl. It is atomic with respect to updates made through any
+	// instance sharing the store, so fn may run more than once. It returns
+	// the job and whether it was saved, or ErrNotFound.
+	Update(ctx context.Context, id string, ttl time.Duration, fn func(*Job) bool) (Job, bool, error)
+}
+
+// Queue hands job IDs from the API to the workers.
+type Queue interface {
+	// Push appends id to the queue.
+	Push(ctx context.Context, id string) error
+	// Pop blocks until an ID is available or ctx ends.
+	Pop(ctx context.Context) (string, error)
+}
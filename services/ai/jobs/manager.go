@@ -0,0 +1,269 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/webhook"
+)
+
+// RunFunc performs the generation for a job, reporting progress as output
+// streams in, and returns its result. It applies its own timeout (BR-003).
+type RunFunc func(ctx context.Context, req models.GenerationRequest, progress func(Progress)) models.GenerationResult
+
+const (
+	// checkInterval is how often a running job saves its progress and checks
+	// whether it was canceled, possibly through another instance.
+	checkInterval = time.Second
+	// popRetryDelay spaces out retries when the queue is unreachable.
+	popRetryDelay = time.Second
+)
+
+// Manager submits, tracks and cancels jobs and runs the worker pool.
+//
+// A job whose worker dies with its instance stays "running" until its
+// retention period ends.
+type Manager struct {
+	store     Store
+	queue     Queue
+	run       RunFunc
+	retention time.Duration
+	notifier  *webhook.Notifier
+	now       func() time.Time
+
+	// mu guards running, the jobs executing on this instance.
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+// NewManager creates a Manager keeping jobs for DefaultRetention. Call Start
+// to begin processing.
+func NewManager(store Store, queue Queue, run RunFunc) *Manager {
+	return &Manager{
+		store:     store,
+		queue:     queue,
+		run:       run,
+		retention: DefaultRetention,
+		now:       time.Now,
+		running:   make(map[string]context.CancelFunc),
+	}
+}
+
+// WithRetention sets how long a job is kept after its last update. Non-positive
+// values keep the default.
+func (m *Manager) WithRetention(d time.Duration) *Manager {
+	if d > 0 {
+		m.retention = d
+	}
+	return m
+}
+
+// WithNotifier enables callbacks: a job whose request has a CallbackURL POSTs
+// its result there when it succeeds or fails. Without a notifier such
+// requests are rejected with ErrCallback.
+func (m *Manager) WithNotifier(n *webhook.Notifier) *Manager {
+	m.notifier = n
+	return m
+}
+
+// Submit queues req and returns the new job.
+func (m *Manager) Submit(ctx context.Context, req models.GenerationRequest) (Job, error) {
+	if req.CallbackURL != "" {
+		if m.notifier == nil {
+			return Job{}, fmt.Errorf("%w: callbacks are not configured", ErrCallback)
+		}
+		if err := m.notifier.ValidateURL(req.CallbackURL); err != nil {
+			return Job{}, fmt.Errorf("%w: %v", ErrCallback, err)
+		}
+	}
+	job := Job{
+		ID:        uuid.New().String(),
+		Status:    StatusQueued,
+		Progress:  Progress{Stage: "queued"},
+		Request:   req,
+		CreatedAt: m.now(),
+	}
+	if err := m.store.Put(ctx, job, m.retention); err != nil {
+		return Job{}, err
+	}
+	if err := m.queue.Push(ctx, job.ID); err != nil {
+		m.update(ctx, job.ID, func(j *Job) bool {
+			m.finish(j, StatusFailed, nil, err.Error())
+			return true
+		})
+		return Job{}, err
+	}
+	return job, nil
+}
+
+// Get returns the job with id, or ErrNotFound.
+func (m *Manager) Get(ctx context.Context, id string) (Job, error) {
+	return m.store.Get(ctx, id)
+}
+
+// Cancel marks the job canceled and stops it if it is running on this
+// instance; a worker on another instance stops at its next progress check.
+// Canceling a finished job returns it with ErrFinished.
+func (m *Manager) Cancel(ctx context.Context, id string) (Job, error) {
+	finished := false
+	job, _, err := m.store.Update(ctx, id, m.retention, func(j *Job) bool {
+		if finished = j.Status.Done(); finished {
+			return false
+		}
+		m.finish(j, StatusCanceled, nil, "canceled")
+		return true
+	})
+	if err != nil {
+		return Job{}, err
+	}
+	if finished {
+		return job, ErrFinished
+	}
+	m.mu.Lock()
+	if cancel, ok := m.running[id]; ok {
+		cancel()
+	}
+	m.mu.Unlock()
+	return job, nil
+}
+
+// Start launches workers goroutines processing jobs until ctx ends.
+func (m *Manager) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go m.work(ctx)
+	}
+}
+
+// work processes jobs from the queue one at a time.
+func (m *Manager) work(ctx context.Context) {
+	for {
+		id, err := m.queue.Pop(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("[ai-service] jobs: %v", err)
+			select {
+			case <-time.After(popRetryDelay):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		m.process(ctx, id)
+	}
+}
+
+// process runs one job, unless it was canceled or expired while queued.
+func (m *Manager) process(ctx context.Context, id string) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	job, ok := m.update(ctx, id, func(j *Job) bool {
+		if j.Status != StatusQueued {
+			return false
+		}
+		started := m.now()
+		j.Status, j.StartedAt = StatusRunning, &started
+		j.Progress = Progress{Stage: "generating"}
+		return true
+	})
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	m.running[id] = cancel
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, id)
+		m.mu.Unlock()
+	}()
+
+	var progressMu sync.Mutex
+	latest := job.Progress
+	report := func(p Progress) {
+		progressMu.Lock()
+		latest = p
+		progressMu.Unlock()
+	}
+	stop := make(chan struct{})
+	checked := make(chan struct{})
+	go func() {
+		defer close(checked)
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			progressMu.Lock()
+			p := latest
+			progressMu.Unlock()
+			canceled := false
+			m.update(ctx, id, func(j *Job) bool {
+				if j.Status != StatusRunning {
+					canceled = true
+					return false
+				}
+				j.Progress = p
+				return true
+			})
+			if canceled {
+				cancel() // through another instance
+				return
+			}
+		}
+	}()
+
+	result := m.run(runCtx, job.Request, report)
+	close(stop)
+	<-checked
+
+	job, saved := m.update(ctx, id, func(j *Job) bool {
+		if j.Status != StatusRunning {
+			return false // canceled while running
+		}
+		j.Progress = latest
+		if result.Status == "success" {
+			m.finish(j, StatusSucceeded, &result, "")
+		} else {
+			m.finish(j, StatusFailed, &result, result.Error)
+		}
+		return true
+	})
+	if saved && job.Request.CallbackURL != "" && m.notifier != nil {
+		// Deliveries retry for a while; don't hold up the worker.
+		go m.notifier.Deliver(context.WithoutCancel(ctx), job.Request.CallbackURL, "job."+string(job.Status), job.ID, result)
+	}
+}
+
+// finish moves j to a final status.
+func (m *Manager) finish(j *Job, status Status, result *models.GenerationResult, errMsg string) {
+	finished := m.now()
+	j.Status, j.Result, j.Error, j.FinishedAt = status, result, errMsg, &finished
+	j.Progress.Stage = "done"
+}
+
+// update applies fn to the stored job and saves it if fn returns true. It
+// reports the job and whether it was saved. The store makes this atomic
+// across instances, so a cancellation is never overwritten by a finishing
+// worker.
+func (m *Manager) update(ctx context.Context, id string, fn func(*Job) bool) (Job, bool) {
+	job, saved, err := m.store.Update(ctx, id, m.retention, fn)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("[ai-service] jobs: update %s: %v", id, err)
+		}
+		return Job{}, false
+	}
+	return job, saved
+}
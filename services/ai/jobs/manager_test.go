@@ -0,0 +1,161 @@
+package jobs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/jobs"
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/webhook"
+)
+
+// waitFor polls the job until cond holds.
+func waitFor(t *testing.T, m *jobs.Manager, id string, cond func(jobs.Job) bool) jobs.Job {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if cond(job) {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting on job %s", id)
+	return jobs.Job{}
+}
+
+func startManager(t *testing.T, store jobs.Store, run jobs.RunFunc) *jobs.Manager {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	m := jobs.NewManager(store, jobs.NewMemoryQueue(8), run)
+	m.Start(ctx, 2)
+	return m
+}
+
+func TestManager_RunsJobToCompletion(t *testing.T) {
+	m := startManager(t, jobs.NewMemoryStore(), func(ctx context.Context, req models.GenerationRequest, progress func(jobs.Progress)) models.GenerationResult {
+		progress(jobs.Progress{Stage: "generating", Provider: "mock", ReceivedChars: 6})
+		return models.GenerationResult{Status: "success", HTML: "<p>" + req.Prompt + "</p>"}
+	})
+
+	job, err := m.Submit(context.Background(), models.GenerationRequest{Prompt: "bakery"})
+	if err != nil || job.Status != jobs.StatusQueued {
+		t.Fatalf("Submit = %+v, %v", job, err)
+	}
+	done := waitFor(t, m, job.ID, func(j jobs.Job) bool { return j.Status.Done() })
+	if done.Status != jobs.StatusSucceeded || done.Result == nil || done.Result.HTML != "<p>bakery</p>" {
+		t.Errorf("finished job = %+v", done)
+	}
+	if done.StartedAt == nil || done.FinishedAt == nil || done.Progress.Stage != "done" {
+		t.Errorf("missing timestamps or final stage: %+v", done)
+	}
+}
+
+func TestManager_CancelStopsRunningJob(t *testing.T) {
+	stopped := make(chan struct{})
+	m := startManager(t, jobs.NewMemoryStore(), func(ctx context.Context, req models.GenerationRequest, progress func(jobs.Progress)) models.GenerationResult {
+		<-ctx.Done()
+		close(stopped)
+		return models.GenerationResult{Status: "error", Error: ctx.Err().Error()}
+	})
+
+	job, _ := m.Submit(context.Background(), models.GenerationRequest{Prompt: "bakery"})
+	waitFor(t, m, job.ID, func(j jobs.Job) bool { return j.Status == jobs.StatusRunning })
+
+	canceled, err := m.Cancel(context.Background(), job.ID)
+	if err != nil || canceled.Status != jobs.StatusCanceled {
+		t.Fatalf("Cancel = %+v, %v", canceled, err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("running job was not stopped")
+	}
+	// The worker's own failure must not overwrite the cancellation.
+	time.Sleep(20 * time.Millisecond)
+	if j, _ := m.Get(context.Background(), job.ID); j.Status != jobs.StatusCanceled {
+		t.Errorf("status after cancel = %s", j.Status)
+	}
+	if _, err := m.Cancel(context.Background(), job.ID); !errors.Is(err, jobs.ErrFinished) {
+		t.Errorf("second Cancel err = %v, want ErrFinished", err)
+	}
+}
+
+func TestManager_JobsExpireAfterRetention(t *testing.T) {
+	now := time.Now()
+	store := jobs.NewMemoryStore().WithClock(func() time.Time { return now })
+	m := jobs.NewManager(store, jobs.NewMemoryQueue(1), nil).WithRetention(time.Hour)
+
+	job, err := m.Submit(context.Background(), models.GenerationRequest{Prompt: "bakery"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(59 * time.Minute)
+	if _, err := m.Get(context.Background(), job.ID); err != nil {
+		t.Fatalf("Get before expiry: %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := m.Get(context.Background(), job.ID); !errors.Is(err, jobs.ErrNotFound) {
+		t.Errorf("Get after retention err = %v, want ErrNotFound", err)
+	}
+
+	if _, err := m.Submit(context.Background(), models.GenerationRequest{Prompt: "another"}); !errors.Is(err, jobs.ErrQueueFull) {
+		t.Errorf("Submit to a full queue err = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestManager_PostsResultToCallback(t *testing.T) {
+	received := make(chan models.GenerationResult, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.Verify([]byte("s3cret"), r.Header.Get(webhook.SignatureHeader), body, time.Minute, time.Now()); err != nil {
+			t.Errorf("callback signature: %v", err)
+		}
+		var result models.GenerationResult
+		json.Unmarshal(body, &result)
+		received <- result
+	}))
+	defer srv.Close()
+
+	run := func(ctx context.Context, req models.GenerationRequest, progress func(jobs.Progress)) models.GenerationResult {
+		return models.GenerationResult{Status: "success", HTML: "<p>bakery</p>"}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := jobs.NewManager(jobs.NewMemoryStore(), jobs.NewMemoryQueue(8), run).
+		WithNotifier(webhook.NewNotifier("s3cret", webhook.Config{AllowPrivate: true}))
+	m.Start(ctx, 1)
+
+	if _, err := m.Submit(ctx, models.GenerationRequest{Prompt: "bakery", CallbackURL: "not a url"}); !errors.Is(err, jobs.ErrCallback) {
+		t.Errorf("invalid callback err = %v, want ErrCallback", err)
+	}
+	if _, err := m.Submit(ctx, models.GenerationRequest{Prompt: "bakery", CallbackURL: srv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case result := <-received:
+		if result.HTML != "<p>bakery</p>" {
+			t.Errorf("callback payload = %+v", result)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("callback was not delivered")
+	}
+}
+
+func TestManager_RejectsCallbackWithoutNotifier(t *testing.T) {
+	m := jobs.NewManager(jobs.NewMemoryStore(), jobs.NewMemoryQueue(1), nil)
+	_, err := m.Submit(context.Background(), models.GenerationRequest{Prompt: "bakery", CallbackURL: "https://example.com/hook"})
+	if !errors.Is(err, jobs.ErrCallback) {
+		t.Errorf("err = %v, want ErrCallback", err)
+	}
+}
@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/zest-app/ai-service/ttlmap"
+)
+
+// MemoryStore is an in-process Store used in tests and when REDIS_URL is not
+// configured. Expired jobs are swept periodically.
+type MemoryStore struct {
+	jobs *ttlmap.Map[Job]
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: ttlmap.New[Job]()}
+}
+
+// WithClock replaces the time source, allowing tests to expire jobs without
+// sleeping.
+func (s *MemoryStore) WithClock(now func() time.Time) *MemoryStore {
+	s.jobs.WithClock(now)
+	return s
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (Job, error) {
+	job, ok := s.jobs.Get(id)
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return job, nil
+}
+
+func (s *MemoryStore) Put(ctx context.Context, job Job, ttl time.Duration) error {
+	s.jobs.Set(job.ID, job, ttl)
+	return nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, id string, ttl time.Duration, fn func(*Job) bool) (Job, bool, error) {
+	job, found, saved := s.jobs.Update(id, ttl, fn)
+	if !found {
+		return Job{}, false, ErrNotFound
+	}
+	return job, saved, nil
+}
+
+// MemoryQueue is an in-process Queue of bounded size.
+type MemoryQueue struct {
+	ids chan string
+}
+
+// NewMemoryQueue creates a MemoryQueue holding up to size pending jobs.
+func NewMemoryQueue(size int) *MemoryQueue {
+	return &MemoryQueue{ids: make(chan string, size)}
+}
+
+func (q *MemoryQueue) Push(ctx context.Context, id string) error {
+	select {
+	case q.ids <- id:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *MemoryQueue) Pop(ctx context.Context) (string, error) {
+	select {
+	case id := <-q.ids:
+		return id, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,141 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisJobPrefix and redisQueueKey follow the gen_cache:{hash} naming of
+	// the result cache.
+	redisJobPrefix = "gen_job:"
+	redisQueueKey  = "gen_jobs:queue"
+	// redisPopTimeout bounds each blocking pop so Pop notices ctx ending.
+	redisPopTimeout = 5 * time.Second
+	// redisUpdateAttempts bounds how often Update retries after another
+	// instance changed the job between its read and its write.
+	redisUpdateAttempts = 10
+)
+
+// RedisStore stores jobs as JSON strings in Redis, relying on key expiry for
+// retention. Update is an optimistic WATCH/MULTI transaction.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// RedisQueue is a Queue on a Redis list, shared by every service instance.
+type RedisQueue struct {
+	client *redis.Client
+}
+
+// NewRedis creates a RedisStore and RedisQueue from a redis:// URL (e.g.
+// REDIS_URL), sharing one connection pool.
+func NewRedis(url string) (*RedisStore, *RedisQueue, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("jobs: parse redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	return &RedisStore{client: client}, &RedisQueue{client: client}, nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (Job, error) {
+	var job Job
+
+	b, err := s.client.Get(ctx, redisJobPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return job, ErrNotFound
+	}
+	if err != nil {
+		return job, fmt.Errorf("jobs: redis get: %w", err)
+	}
+	if err := json.Unmarshal(b, &job); err != nil {
+		return job, fmt.Errorf("jobs: decode job: %w", err)
+	}
+	return job, nil
+}
+
+func (s *RedisStore) Put(ctx context.Context, job Job, ttl time.Duration) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("jobs: encode job: %w", err)
+	}
+	if err := s.client.Set(ctx, redisJobPrefix+job.ID, b, ttl).Err(); err != nil {
+		return fmt.Errorf("jobs: redis set: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Update(ctx context.Context, id string, ttl time.Duration, fn func(*Job) bool) (Job, bool, error) {
+	key := redisJobPrefix + id
+	for attempt := 0; attempt < redisUpdateAttempts; attempt++ {
+		var (
+			job   Job
+			saved bool
+		)
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			b, err := tx.Get(ctx, key).Bytes()
+			if errors.Is(err, redis.Nil) {
+				return ErrNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("jobs: redis get: %w", err)
+			}
+			job = Job{}
+			if err := json.Unmarshal(b, &job); err != nil {
+				return fmt.Errorf("jobs: decode job: %w", err)
+			}
+			if !fn(&job) {
+				return nil
+			}
+			nb, err := json.Marshal(job)
+			if err != nil {
+				return fmt.Errorf("jobs: encode job: %w", err)
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, nb, ttl)
+				return nil
+			})
+			if err == nil {
+				saved = true
+			}
+			return err
+		}, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue // changed by another instance; read it again
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			err = fmt.Errorf("jobs: redis update: %w", err)
+		}
+		return job, saved, err
+	}
+	return Job{}, false, fmt.Errorf("jobs: redis update %s: too much contention", id)
+}
+
+func (q *RedisQueue) Push(ctx context.Context, id string) error {
+	if err := q.client.LPush(ctx, redisQueueKey, id).Err(); err != nil {
+		return fmt.Errorf("jobs: redis push: %w", err)
+	}
+	return nil
+}
+
+func (q *RedisQueue) Pop(ctx context.Context) (string, error) {
+	for {
+		res, err := q.client.BRPop(ctx, redisPopTimeout, redisQueueKey).Result()
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if errors.Is(err, redis.Nil) {
+			continue // timed out with nothing queued
+		}
+		if err != nil {
+			return "", fmt.Errorf("jobs: redis pop: %w", err)
+		}
+		return res[1], nil // [key, value]
+	}
+}
@@ -1,16 +1,32 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/zest-app/ai-service/cache"
+	"github.com/zest-app/ai-service/config"
 	"github.com/zest-app/ai-service/handlers"
+	"github.com/zest-app/ai-service/idempotency"
+	"github.com/zest-app/ai-service/jobs"
 	"github.com/zest-app/ai-service/moderator"
 	"github.com/zest-app/ai-service/providers"
+	"github.com/zest-app/ai-service/validator"
+	"github.com/zest-app/ai-service/webhook"
+)
+
+// Job worker pool defaults, overridden by JOB_WORKERS. The in-memory queue
+// rejects new jobs beyond defaultJobQueueSize pending ones.
+const (
+	defaultJobWorkers   = 4
+	defaultJobQueueSize = 256
 )
 
 func main() {
@@ -22,16 +38,116 @@ func main() {
 	// Wire up dependencies
 	mod := moderator.New()
 
-	router := providers.NewRouter(
-		providers.NewGeminiProvider(),  // Primary
-		providers.NewGLMProvider(),     // Secondary
-		providers.NewCopilotProvider(), // Tertiary
+	// BR-005: provider order comes from the config file, or GLM → Gemini →
+	// Copilot when AI_PROVIDERS_CONFIG is unset.
+	cfgPath := os.Getenv("AI_PROVIDERS_CONFIG")
+	cfg := config.Default()
+	if cfgPath != "" {
+		var err error
+		if cfg, err = config.Load(cfgPath); err != nil {
+			log.Fatalf("[ai-service] fatal: %v", err)
+		}
+	}
+	ps, err := cfg.Build()
+	if err != nil {
+		log.Fatalf("[ai-service] fatal: %v", err)
+	}
+	router := providers.NewRouter(ps...).
+		WithValidators(validator.CheckOutput).
+		WithStrategy(cfg.Routing.Strategy).
+		WithHedgeDelay(cfg.Routing.HedgeDelay)
+
+	// Reload on SIGHUP or file change; in-flight requests finish on the
+	// providers they started with.
+	if cfgPath != "" {
+		go config.Watch(context.Background(), cfgPath, config.DefaultPollInterval, func(c config.Config) error {
+			ps, err := c.Build()
+			if err != nil {
+				return err
+			}
+			router.Reload(ps...)
+			router.WithStrategy(c.Routing.Strategy).WithHedgeDelay(c.Routing.HedgeDelay)
+			log.Printf("[ai-service] loaded %d providers from %s", len(ps), cfgPath)
+			return nil
+		})
+	}
+
+	// BR-007: Redis-backed result cache, in-memory when REDIS_URL is unset
+	var resultCache cache.Cache = cache.NewMemoryCache()
+	if url := os.Getenv("REDIS_URL"); url != "" {
+		rc, err := cache.NewRedisCache(url)
+		if err != nil {
+			log.Fatalf("[ai-service] fatal: %v", err)
+		}
+		resultCache = rc
+	}
+
+	// Async jobs share Redis with the cache when configured, so any instance
+	// can answer GET /jobs/{id} and pick up queued work.
+	var (
+		jobStore jobs.Store = jobs.NewMemoryStore()
+		jobQueue jobs.Queue = jobs.NewMemoryQueue(defaultJobQueueSize)
 	)
+	if url := os.Getenv("REDIS_URL"); url != "" {
+		rs, rq, err := jobs.NewRedis(url)
+		if err != nil {
+			log.Fatalf("[ai-service] fatal: %v", err)
+		}
+		jobStore, jobQueue = rs, rq
+	}
+	retention := jobs.DefaultRetention
+	if v := os.Getenv("JOB_RETENTION"); v != "" {
+		if retention, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("[ai-service] fatal: JOB_RETENTION: %v", err)
+		}
+	}
+	workers := defaultJobWorkers
+	if v := os.Getenv("JOB_WORKERS"); v != "" {
+		if workers, err = strconv.Atoi(v); err != nil || workers < 1 {
+			log.Fatalf("[ai-service] fatal: JOB_WORKERS must be a positive integer, got %q", v)
+		}
+	}
+	jobManager := jobs.NewManager(jobStore, jobQueue, handlers.NewJobRunner(router, resultCache)).
+		WithRetention(retention)
+
+	// Job callbacks are signed, so they are only accepted once a secret is set.
+	var notifier *webhook.Notifier
+	if secret := os.Getenv("WEBHOOK_SECRET"); secret != "" {
+		cfg := webhook.DefaultConfig
+		// Receivers on a private network (e.g. a compose service) need this.
+		cfg.AllowPrivate = os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true"
+		notifier = webhook.NewNotifier(secret, cfg)
+		jobManager.WithNotifier(notifier)
+	}
+	jobManager.Start(context.Background(), workers)
+
+	// Idempotency keys live in Redis with the cache when configured, so a
+	// retry landing on another instance is still recognized.
+	var idemStore idempotency.Store = idempotency.NewMemoryStore()
+	if url := os.Getenv("REDIS_URL"); url != "" {
+		is, err := idempotency.NewRedisStore(url)
+		if err != nil {
+			log.Fatalf("[ai-service] fatal: %v", err)
+		}
+		idemStore = is
+	}
+	idemWindow := idempotency.DefaultWindow
+	if v := os.Getenv("IDEMPOTENCY_WINDOW"); v != "" {
+		if idemWindow, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("[ai-service] fatal: IDEMPOTENCY_WINDOW: %v", err)
+		}
+	}
 
-	generateHandler := handlers.NewGenerateHandler(router, mod)
+	generateHandler := handlers.NewGenerateHandler(router, mod, resultCache).
+		WithIdempotency(idemStore, idemWindow)
+	streamHandler := handlers.NewStreamHandler(router, mod, resultCache)
+	batchHandler := handlers.NewBatchHandler(generateHandler)
 	refineHandler := handlers.NewRefineHandler(router, mod)
 	moderateHandler := handlers.NewModerateHandler(mod)
 	modelsHandler := handlers.NewModelsHandler(router)
+	statsHandler := handlers.NewStatsHandler(router)
+	jobsHandler := handlers.NewJobsHandler(router, mod, jobManager)
+	deliveriesHandler := handlers.NewDeliveriesHandler(notifier)
 
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
@@ -45,9 +161,16 @@ func main() {
 	})
 
 	r.Get("/models", modelsHandler.ServeHTTP)
+	r.Get("/providers/stats", statsHandler.ServeHTTP)
 	r.Post("/generate", generateHandler.ServeHTTP)
+	r.Post("/generate/stream", streamHandler.ServeHTTP)
+	r.Post("/generate/batch", batchHandler.ServeHTTP)
 	r.Post("/refine", refineHandler.ServeHTTP)
 	r.Post("/moderate", moderateHandler.ServeHTTP)
+	r.Post("/jobs", jobsHandler.Create)
+	r.Get("/jobs/{id}", jobsHandler.Get)
+	r.Delete("/jobs/{id}", jobsHandler.Delete)
+	r.Get("/webhooks/deliveries", deliveriesHandler.ServeHTTP)
 
 	log.Printf("[ai-service] listening on :%s", port)
 	if err := http.ListenAndServe(":"+port, r); err != nil {
@@ -10,9 +10,14 @@ type GenerationRequest struct {
 	Preferences GenPrefs   `json:"preferences"`
 	// PreferredProvider and PreferredModel allow the caller to pin a specific
 	// provider/model. The router will try this provider first; if it fails it
-	// falls back to the normal order.
+	// falls back to the normal order. Unknown values are rejected with 400.
 	PreferredProvider string `json:"preferred_provider,omitempty"` // e.g. "gemini"
 	PreferredModel    string `json:"preferred_model,omitempty"`    // e.g. "gemini-2.5-pro"
+	// StrictProvider disables fallback: only PreferredProvider is tried.
+	StrictProvider bool `json:"strict_provider,omitempty"`
+	// CallbackURL receives the final GenerationResult of a job as a signed
+	// POST (see package webhook). Only POST /jobs honors it.
+	CallbackURL string `json:"callback_url,omitempty"`
 }
 
 // GenContext carries refinement targeting metadata.
@@ -23,24 +28,61 @@ type GenContext struct {
 	// refinement handler can build a scoped prompt (BR-021).
 	PreviousHTML string `json:"previous_html,omitempty"`
 	PreviousCSS  string `json:"previous_css,omitempty"`
+	// PartialOutput is set by the router when asking a provider to continue a
+	// response that was cut off at the token limit. Never sent by callers.
+	PartialOutput string `json:"-"`
 }
 
 // GenPrefs holds output format and style hints.
 type GenPrefs struct {
 	OutputFormat string `json:"output_format"` // "html_css" | "tailwind"
 	StyleHints   string `json:"style_hints,omitempty"`
+	// AllowedOrigins lists remote hosts the generated page may load from, e.g.
+	// "https://fonts.googleapis.com" when the user asked for Google Fonts.
+	// All other external resources are stripped (BR-011).
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
 }
 
 // GenerationResult is the normalized response returned to Next.js.
 type GenerationResult struct {
 	GenerationID string `json:"generation_id"`
-	Status       string `json:"status"` // "success" | "error"
+	Status       string `json:"status"` // "success" | "error" | "moderated"
 	HTML         string `json:"html"`
 	CSS          string `json:"css"`
 	ProviderUsed string `json:"provider_used"`
+	ModelUsed    string `json:"model_used,omitempty"`
 	DurationMs   int64  `json:"duration_ms"`
-	TokenCount   int    `json:"token_count"`
-	Error        string `json:"error,omitempty"`
+	// TokenCount is PromptTokens + CompletionTokens as reported by the provider.
+	// All three are zero on a cache hit since no provider call was made.
+	TokenCount       int    `json:"token_count"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	FinishReason     string `json:"finish_reason,omitempty"`
+	CacheHit         bool   `json:"cache_hit"` // served from the result cache (BR-007)
+	// Coalesced is set when the result was shared from an identical request
+	// already in flight; like a cache hit it spent no tokens of its own.
+	Coalesced bool   `json:"coalesced,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	Validation   *ValidationReport   `json:"validation,omitempty"`
+	Sanitization *SanitizationReport `json:"sanitization,omitempty"`
+}
+
+// ValidationReport describes the HTML5 repairs applied to generated output
+// (BR-009). Truncated is set when the provider output was cut off, so the
+// caller can offer to continue the generation.
+type ValidationReport struct {
+	Fixed     []string `json:"fixed,omitempty"`
+	Truncated bool     `json:"truncated"`
+}
+
+// SanitizationReport lists content stripped from generated output. Removed
+// holds executable content (BR-010), e.g. "<script>" or "onclick on <button>";
+// External holds remote resources that were dropped or replaced with a local
+// placeholder (BR-011). Omitted when nothing was changed.
+type SanitizationReport struct {
+	Removed  []string `json:"removed,omitempty"`
+	External []string `json:"external,omitempty"`
 }
 
 // ModerationRequest is the payload for the /moderate endpoint.
@@ -59,4 +101,9 @@ type ModerationResult struct {
 // ErrorResponse is a standardized error payload.
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Code identifies the error for programmatic handling, e.g. "invalid_model".
+	Code string `json:"code,omitempty"`
+	// ValidChoices lists the enabled providers and their model IDs when a
+	// preferred provider or model was rejected.
+	ValidChoices map[string][]string `json:"valid_choices,omitempty"`
 }
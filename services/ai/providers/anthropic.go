@@ -0,0 +1,293 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/zest-app/ai-service/models"
+)
+
+const anthropicSystemPrompt = `You are an expert HTML/CSS developer.
+Given a user's description, generate a complete, self-contained HTML page with embedded CSS.
+Output ONLY the raw HTML — no explanations, no markdown, no code fences.
+The HTML must include a <style> block inside <head> for all CSS.
+Use semantic HTML5 elements, responsive design, and modern CSS.`
+
+// anthropicVersion is sent as the anthropic-version header.
+const anthropicVersion = "2023-06-01"
+
+// anthropicDefaults are used for any Settings field left empty.
+var anthropicDefaults = Settings{
+	BaseURL:      "https://api.anthropic.com/v1",
+	DefaultModel: "claude-sonnet-4-5",
+	Models: []ModelInfo{
+		{ID: "claude-sonnet-4-5", DisplayName: "Claude Sonnet 4.5", Provider: "anthropic"},
+		{ID: "claude-haiku-4-5", DisplayName: "Claude Haiku 4.5", Provider: "anthropic"},
+	},
+}
+
+// AnthropicProvider calls Claude models via the Anthropic Messages API.
+type AnthropicProvider struct {
+	settings Settings
+	client   *http.Client
+	catalog  *modelCatalog
+	keys     *KeyPool
+}
+
+// NewAnthropicProvider creates an AnthropicProvider. Enabled() returns false
+// when no API key is configured.
+func NewAnthropicProvider(s Settings) *AnthropicProvider {
+	s = s.withDefaults(anthropicDefaults)
+	p := &AnthropicProvider{settings: s, client: s.httpClient(), keys: s.keyPool()}
+	p.catalog = newModelCatalog("anthropic", s, p.listModels)
+	return p
+}
+
+func (p *AnthropicProvider) Name() string  { return "anthropic" }
+func (p *AnthropicProvider) Enabled() bool { return p.keys.Len() > 0 }
+
+// Models returns the models discovered from GET /models when discovery is
+// enabled, otherwise the configured list.
+func (p *AnthropicProvider) Models() []ModelInfo { return p.catalog.models() }
+
+// KeyStatus reports usage of each pooled API key.
+func (p *AnthropicProvider) KeyStatus() []KeyStatus { return p.keys.Status() }
+
+// listModels fetches the available models from GET /models.
+func (p *AnthropicProvider) listModels(ctx context.Context) ([]ModelInfo, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.settings.BaseURL+"/models?limit=1000", nil)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: new request: %w", err)
+	}
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := p.send(httpReq, "list models")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list struct {
+		Data []struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"display_name"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("anthropic: decode models: %w", err)
+	}
+	out := make([]ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		out = append(out, ModelInfo{ID: m.ID, DisplayName: m.DisplayName})
+	}
+	return out, nil
+}
+
+func (p *AnthropicProvider) Generate(ctx context.Context, req models.GenerationRequest) (Response, error) {
+	resp, err := p.doRequest(ctx, req, false)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	return extractAnthropicContent(resp.Body)
+}
+
+// GenerateStream implements StreamingProvider using `stream: true`, which
+// returns typed Server-Sent Events (message_start, content_block_delta,
+// message_delta, ...).
+func (p *AnthropicProvider) GenerateStream(ctx context.Context, req models.GenerationRequest, onChunk func(string) error) (Response, error) {
+	resp, err := p.doRequest(ctx, req, true)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	return streamAnthropicContent(resp.Body, onChunk)
+}
+
+// doRequest sends a Messages API request and returns the response once a 200
+// status has been received. The caller must close the body.
+func (p *AnthropicProvider) doRequest(ctx context.Context, req models.GenerationRequest, stream bool) (*http.Response, error) {
+	model := p.settings.model(req, p.Name())
+	temperature, maxTokens := p.settings.params(model)
+	system, messages := anthropicMessages(req)
+	payload := map[string]any{
+		"model":       model,
+		"system":      system,
+		"messages":    messages,
+		"temperature": temperature,
+		"max_tokens":  maxTokens,
+		"stream":      stream,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: marshal: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		p.settings.BaseURL+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: new request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	return p.send(httpReq, "do request")
+}
+
+// anthropicMessages splits the chat transcript into the Messages API's
+// top-level system prompt and its alternating user/assistant turns.
+func anthropicMessages(req models.GenerationRequest) (string, []map[string]string) {
+	var system string
+	var turns []map[string]string
+	for _, m := range chatMessages(req, anthropicSystemPrompt) {
+		if m["role"] == "system" {
+			system = m["content"]
+			continue
+		}
+		turns = append(turns, m)
+	}
+	return system, turns
+}
+
+// anthropicUsage is the token usage block of a Messages API response.
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicMessage is the shape of a Messages API response.
+type anthropicMessage struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string          `json:"stop_reason"`
+	Usage      *anthropicUsage `json:"usage"`
+}
+
+// extractAnthropicContent decodes a Messages API response, joining its text
+// content blocks.
+func extractAnthropicContent(r io.Reader) (Response, error) {
+	var msg anthropicMessage
+	if err := json.NewDecoder(r).Decode(&msg); err != nil {
+		return Response{}, fmt.Errorf("decode anthropic response: %w", err)
+	}
+	var sb strings.Builder
+	for _, block := range msg.Content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	resp := Response{Text: sb.String(), FinishReason: msg.StopReason, Model: msg.Model}
+	if msg.Usage != nil {
+		resp.PromptTokens = msg.Usage.InputTokens
+		resp.CompletionTokens = msg.Usage.OutputTokens
+	}
+	if resp.Text == "" && !resp.Blocked() {
+		return Response{}, fmt.Errorf("anthropic: no text content in response")
+	}
+	return resp, nil
+}
+
+// anthropicStreamEvent is the data payload of one streaming event. Only the
+// fields used by the events we handle are declared.
+type anthropicStreamEvent struct {
+	Type    string            `json:"type"`
+	Message *anthropicMessage `json:"message"`
+	Delta   struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// streamAnthropicContent reads a Messages API event stream, forwarding text
+// deltas to onChunk. Input tokens arrive with message_start, the stop reason
+// and final output token count with message_delta.
+func streamAnthropicContent(r io.Reader, onChunk func(string) error) (Response, error) {
+	var resp Response
+	var sb strings.Builder
+	err := readSSE(r, func(data string) error {
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return fmt.Errorf("decode anthropic stream event: %w", err)
+		}
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil {
+				resp.Model = ev.Message.Model
+				if ev.Message.Usage != nil {
+					resp.PromptTokens = ev.Message.Usage.InputTokens
+					resp.CompletionTokens = ev.Message.Usage.OutputTokens
+				}
+			}
+		case "content_block_delta":
+			if ev.Delta.Type != "text_delta" || ev.Delta.Text == "" {
+				return nil
+			}
+			sb.WriteString(ev.Delta.Text)
+			return onChunk(ev.Delta.Text)
+		case "message_delta":
+			if ev.Delta.StopReason != "" {
+				resp.FinishReason = ev.Delta.StopReason
+			}
+			if ev.Usage != nil {
+				resp.CompletionTokens = ev.Usage.OutputTokens
+			}
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			return anthropicStreamError(ev)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStreamDone) {
+		return Response{}, err
+	}
+	if sb.Len() == 0 && !resp.Blocked() {
+		return Response{}, fmt.Errorf("anthropic: empty stream")
+	}
+	resp.Text = sb.String()
+	return resp, nil
+}
+
+// anthropicStreamError classifies an error event sent after the 200 response
+// headers, e.g. overloaded_error, so the router can retry or fall back.
+func anthropicStreamError(ev anthropicStreamEvent) *Error {
+	e := &Error{Provider: "anthropic", Kind: ErrServer}
+	if ev.Error != nil {
+		e.Message = ev.Error.Type + ": " + ev.Error.Message
+		switch ev.Error.Type {
+		case "rate_limit_error":
+			e.Kind = ErrRateLimited
+		case "invalid_request_error":
+			e.Kind = ErrBadRequest
+		case "authentication_error", "permission_error":
+			e.Kind = ErrAuth
+		}
+	}
+	return e
+}
+
+// send attaches a pooled API key to httpReq and sends it; see sendWithKey.
+func (p *AnthropicProvider) send(httpReq *http.Request, op string) (*http.Response, error) {
+	return sendWithKey(p.client, p.keys, "anthropic", op, httpReq, func(r *http.Request, key string) error {
+		r.Header.Set("x-api-key", key)
+		return nil
+	})
+}
@@ -0,0 +1,134 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/providers"
+)
+
+// anthropicStub stands in for the Messages API and records the last request.
+type anthropicStub struct {
+	*httptest.Server
+	header  http.Header
+	payload struct {
+		Model    string              `json:"model"`
+		System   string              `json:"system"`
+		Messages []map[string]string `json:"messages"`
+		Stream   bool                `json:"stream"`
+	}
+}
+
+func newAnthropicStub(t *testing.T, respond func(w http.ResponseWriter, stream bool)) *anthropicStub {
+	t.Helper()
+	s := &anthropicStub{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			http.NotFound(w, r)
+			return
+		}
+		s.header = r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&s.payload)
+		respond(w, s.payload.Stream)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newAnthropic(s *anthropicStub) *providers.AnthropicProvider {
+	return providers.NewAnthropicProvider(providers.Settings{APIKey: "sk-ant", BaseURL: s.URL + "/v1"})
+}
+
+func TestAnthropic_Generate(t *testing.T) {
+	stub := newAnthropicStub(t, func(w http.ResponseWriter, _ bool) {
+		fmt.Fprint(w, `{"model":"claude-haiku-4-5","content":[{"type":"text","text":"<html>"},{"type":"text","text":"</html>"}],"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":6}}`)
+	})
+	p := newAnthropic(stub)
+
+	req := models.GenerationRequest{Prompt: "a bakery", PreferredProvider: "anthropic", PreferredModel: "claude-haiku-4-5"}
+	resp, err := p.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if resp.Text != "<html></html>" || resp.PromptTokens != 12 || resp.CompletionTokens != 6 || resp.FinishReason != "end_turn" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if stub.header.Get("x-api-key") != "sk-ant" || stub.header.Get("anthropic-version") == "" {
+		t.Errorf("missing auth headers: %v", stub.header)
+	}
+	if stub.payload.Model != "claude-haiku-4-5" || stub.payload.System == "" {
+		t.Errorf("unexpected payload: %+v", stub.payload)
+	}
+	for _, m := range stub.payload.Messages {
+		if m["role"] == "system" {
+			t.Error("system prompt must go in the top-level system field")
+		}
+	}
+}
+
+func TestAnthropic_GenerateStream(t *testing.T) {
+	stub := newAnthropicStub(t, func(w http.ResponseWriter, _ bool) {
+		events := []string{
+			`{"type":"message_start","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":20,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"<html><p>Hi"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"</p>"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":8}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, e := range events {
+			var typ struct{ Type string }
+			json.Unmarshal([]byte(e), &typ)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, e)
+		}
+	})
+	p := newAnthropic(stub)
+
+	var chunks []string
+	resp, err := p.GenerateStream(context.Background(), models.GenerationRequest{}, func(s string) error {
+		chunks = append(chunks, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream: %v", err)
+	}
+	if strings.Join(chunks, "") != "<html><p>Hi</p>" || len(chunks) != 2 {
+		t.Errorf("chunks = %q", chunks)
+	}
+	if resp.Model != "claude-sonnet-4-5" || resp.PromptTokens != 20 || resp.CompletionTokens != 8 || !resp.Truncated() {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestAnthropic_StreamErrorIsClassified(t *testing.T) {
+	stub := newAnthropicStub(t, func(w http.ResponseWriter, _ bool) {
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	})
+	_, err := newAnthropic(stub).GenerateStream(context.Background(), models.GenerationRequest{}, func(string) error { return nil })
+
+	var perr *providers.Error
+	if !errors.As(err, &perr) || !perr.Retryable() {
+		t.Fatalf("expected a retryable provider error, got %v", err)
+	}
+}
+
+func TestAnthropic_RefusalIsBlocked(t *testing.T) {
+	stub := newAnthropicStub(t, func(w http.ResponseWriter, _ bool) {
+		fmt.Fprint(w, `{"model":"claude-sonnet-4-5","content":[],"stop_reason":"refusal","usage":{"input_tokens":5,"output_tokens":0}}`)
+	})
+	r := providers.NewRouter(newAnthropic(stub))
+
+	_, err := r.Route(context.Background(), models.GenerationRequest{PreferredProvider: "anthropic"})
+	if !errors.Is(err, providers.ErrContentFiltered) {
+		t.Errorf("expected ErrContentFiltered, got %v", err)
+	}
+}
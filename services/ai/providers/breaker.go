@@ -0,0 +1,143 @@
+package providers
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a provider circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed lets every request through.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen skips the provider until the cool-down has elapsed.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe request through; its outcome decides
+	// whether the breaker closes again or re-opens.
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// BreakerConfig configures the per-provider circuit breakers.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// breaker.
+	FailureThreshold int
+	// CoolDown is how long an open breaker skips its provider before allowing
+	// a probe request.
+	CoolDown time.Duration
+	// Now is the clock; tests inject a fake one. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// DefaultBreakerConfig opens after 5 consecutive failures and probes again
+// after 30 seconds — half the BR-003 budget, so a recovering provider is
+// retried quickly without stalling every request during an outage.
+var DefaultBreakerConfig = BreakerConfig{
+	FailureThreshold: 5,
+	CoolDown:         30 * time.Second,
+}
+
+// BreakerStatus is a snapshot of a breaker, exposed through GET /models.
+type BreakerStatus struct {
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	// RetryAt is when an open breaker will allow a probe request.
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+}
+
+// Breaker is a closed/open/half-open circuit breaker for one provider.
+type Breaker struct {
+	mu       sync.Mutex
+	cfg      BreakerConfig
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreaker creates a closed Breaker. Zero fields in cfg take their values
+// from DefaultBreakerConfig.
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultBreakerConfig.FailureThreshold
+	}
+	if cfg.CoolDown <= 0 {
+		cfg.CoolDown = DefaultBreakerConfig.CoolDown
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Breaker{cfg: cfg, state: BreakerClosed}
+}
+
+// Allow reports whether a request may be sent to the provider. An open breaker
+// whose cool-down has elapsed moves to half-open and admits one probe; the
+// caller must then report Success, Failure or Abandon.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.cfg.Now().Sub(b.openedAt) < b.cfg.CoolDown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// Success records a successful request and closes the breaker.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure records a failed request. The breaker opens once FailureThreshold
+// consecutive failures are reached, or immediately when a half-open probe fails.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == BreakerHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = b.cfg.Now()
+	}
+}
+
+// Abandon releases a request that ended without saying anything about the
+// provider's health, e.g. because the caller disconnected.
+func (b *Breaker) Abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// Status returns a snapshot of the breaker.
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := BreakerStatus{State: b.state, ConsecutiveFailures: b.failures}
+	if b.state == BreakerOpen {
+		retryAt := b.openedAt.Add(b.cfg.CoolDown)
+		s.RetryAt = &retryAt
+	}
+	return s
+}
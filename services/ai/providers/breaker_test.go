@@ -0,0 +1,107 @@
+package providers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/providers"
+)
+
+// fakeClock is an injectable time source.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	b := providers.NewBreaker(providers.BreakerConfig{FailureThreshold: 2, CoolDown: time.Minute, Now: clock.Now})
+
+	b.Failure()
+	if !b.Allow() {
+		t.Fatal("expected breaker to stay closed below the threshold")
+	}
+	b.Failure()
+	if b.Allow() {
+		t.Fatal("expected breaker to open at the threshold")
+	}
+	if s := b.Status(); s.State != providers.BreakerOpen || s.RetryAt == nil || !s.RetryAt.Equal(clock.t.Add(time.Minute)) {
+		t.Errorf("unexpected status: %+v", s)
+	}
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	b := providers.NewBreaker(providers.BreakerConfig{FailureThreshold: 1, CoolDown: time.Minute, Now: clock.Now})
+	b.Failure()
+
+	clock.Advance(time.Minute)
+	if !b.Allow() {
+		t.Fatal("expected a probe to be allowed after the cool-down")
+	}
+	if b.Allow() {
+		t.Fatal("expected only one concurrent probe in half-open state")
+	}
+	if s := b.Status(); s.State != providers.BreakerHalfOpen {
+		t.Errorf("state = %s, want half_open", s.State)
+	}
+
+	b.Failure()
+	if b.Allow() {
+		t.Fatal("expected a failed probe to re-open the breaker")
+	}
+
+	clock.Advance(time.Minute)
+	b.Allow()
+	b.Success()
+	if s := b.Status(); s.State != providers.BreakerClosed || s.ConsecutiveFailures != 0 {
+		t.Errorf("expected breaker to close after a successful probe, got %+v", s)
+	}
+}
+
+func TestRoute_SkipsOpenBreaker(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	down := &fakeProvider{name: "a", enabled: true, err: errors.New("503")}
+	up := &fakeProvider{name: "b", enabled: true, out: "<html></html>"}
+	r := providers.NewRouter(down, up).WithBreakerConfig(providers.BreakerConfig{
+		FailureThreshold: 2, CoolDown: time.Minute, Now: clock.Now,
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Route(context.Background(), models.GenerationRequest{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if down.calls != 2 {
+		t.Fatalf("expected 2 calls before the breaker opened, got %d", down.calls)
+	}
+
+	resp, err := r.Route(context.Background(), models.GenerationRequest{})
+	if err != nil || resp.Provider != "b" {
+		t.Fatalf("expected fallback to b, got provider=%q err=%v", resp.Provider, err)
+	}
+	if down.calls != 2 {
+		t.Errorf("expected open provider to be skipped, got %d calls", down.calls)
+	}
+
+	info := r.AvailableProviders()
+	if info[0].Circuit.State != providers.BreakerOpen || info[1].Circuit.State != providers.BreakerClosed {
+		t.Errorf("unexpected circuit states: %s, %s", info[0].Circuit.State, info[1].Circuit.State)
+	}
+}
+
+func TestRoute_InvalidOutputDoesNotTripBreaker(t *testing.T) {
+	p := &fakeProvider{name: "a", enabled: true, out: "prose"}
+	r := providers.NewRouter(p).
+		WithValidators(func(string) error { return errors.New("bad") }).
+		WithBreakerConfig(providers.BreakerConfig{FailureThreshold: 1})
+
+	r.Route(context.Background(), models.GenerationRequest{})
+
+	if s := r.AvailableProviders()[0].Circuit; s.State != providers.BreakerClosed {
+		t.Errorf("expected breaker to stay closed on invalid output, got %+v", s)
+	}
+}
@@ -0,0 +1,218 @@
+package providers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// Cassette modes.
+const (
+	// CassetteRecord forwards requests to the real provider and saves each
+	// exchange to the cassette directory.
+	CassetteRecord = "record"
+	// CassetteReplay answers requests from the cassette directory without
+	// touching the network.
+	CassetteReplay = "replay"
+)
+
+// secretParams are query parameters left out of recorded URLs and request
+// keys. Request headers are never recorded.
+var secretParams = []string{"key", "api_key"}
+
+// secretFields are JSON response fields whose values are replaced with
+// redactedValue when recorded, e.g. the session token returned by Copilot's
+// token exchange. Replayed responses carry the placeholder instead.
+var secretFields = map[string]bool{
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+}
+
+const redactedValue = "REDACTED"
+
+// Cassette is an http.RoundTripper that records provider HTTP exchanges to
+// files and replays them offline. Each exchange is stored in Dir as
+// <sha256 of method, URL and body>.json, so identical requests share a file.
+// Streamed responses are recorded in full and replayed in one piece.
+type Cassette struct {
+	Mode string
+	Dir  string
+	// Next performs real requests in record mode; nil means
+	// http.DefaultTransport.
+	Next http.RoundTripper
+}
+
+// NewCassette returns a Cassette in mode ("record" or "replay") storing
+// exchanges in dir.
+func NewCassette(mode, dir string) (*Cassette, error) {
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, fmt.Errorf("cassette: unknown mode %q", mode)
+	}
+	return &Cassette{Mode: mode, Dir: dir}, nil
+}
+
+// interaction is the on-disk form of one exchange.
+type interaction struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+		Body   string `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		Status int               `json:"status"`
+		Header map[string]string `json:"header,omitempty"`
+		Body   string            `json:"body"`
+	} `json:"response"`
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("cassette: read request: %w", err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	u := redactURL(req.URL)
+	path := filepath.Join(c.Dir, interactionKey(req.Method, u, body)+".json")
+
+	if c.Mode == CassetteReplay {
+		return c.replay(req, path, u)
+	}
+	return c.record(req, path, u, body)
+}
+
+func (c *Cassette) replay(req *http.Request, path, u string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: no recorded interaction for %s %s: %w", req.Method, u, err)
+	}
+	var in interaction
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("cassette: decode %s: %w", path, err)
+	}
+	resp := &http.Response{
+		StatusCode:    in.Response.Status,
+		Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(bytes.NewReader([]byte(in.Response.Body))),
+		ContentLength: int64(len(in.Response.Body)),
+		Request:       req,
+	}
+	for k, v := range in.Response.Header {
+		resp.Header.Set(k, v)
+	}
+	return resp, nil
+}
+
+func (c *Cassette) record(req *http.Request, path, u string, body []byte) (*http.Response, error) {
+	next := c.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cassette: read response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	var in interaction
+	in.Request.Method, in.Request.URL, in.Request.Body = req.Method, u, string(body)
+	in.Response.Status, in.Response.Body = resp.StatusCode, redactBody(respBody)
+	in.Response.Header = map[string]string{}
+	for _, k := range []string{"Content-Type", "Retry-After"} {
+		if v := resp.Header.Get(k); v != "" {
+			in.Response.Header[k] = v
+		}
+	}
+	data, err := json.MarshalIndent(in, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("cassette: encode: %w", err)
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	return resp, nil
+}
+
+// redactURL returns u as a string without secretParams.
+func redactURL(u *url.URL) string {
+	c := *u
+	q := c.Query()
+	for _, p := range secretParams {
+		q.Del(p)
+	}
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+// redactBody returns a JSON body with secretFields replaced at any depth.
+// Bodies that are not JSON, or hold no secrets, are returned unchanged.
+func redactBody(body []byte) string {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // keep numbers exactly as sent
+	if err := dec.Decode(&v); err != nil || !redactValue(v) {
+		return string(body)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return string(body)
+	}
+	return string(out)
+}
+
+// redactValue replaces secretFields in v in place and reports whether any
+// were found.
+func redactValue(v any) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if _, isString := field.(string); isString && secretFields[k] {
+				v[k] = redactedValue
+				found = true
+			} else if redactValue(field) {
+				found = true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if redactValue(item) {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// interactionKey identifies an exchange by method, redacted URL and body.
+func interactionKey(method, u string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, u)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,83 @@
+package providers_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/providers"
+)
+
+func TestCassette_RecordThenReplayOffline(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"candidates":[{"content":{"parts":[{"text":"<html></html>"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":2}}`)
+	}))
+	dir := t.TempDir()
+	req := models.GenerationRequest{Prompt: "a recorded page"}
+
+	rec, _ := providers.NewCassette(providers.CassetteRecord, dir)
+	live := providers.NewGeminiProvider(providers.Settings{APIKey: "real-secret", BaseURL: srv.URL, Transport: rec})
+	want, err := live.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	srv.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("expected one recorded interaction, got %d", len(files))
+	}
+	if data, _ := os.ReadFile(files[0]); strings.Contains(string(data), "real-secret") {
+		t.Error("cassette must not contain the API key")
+	}
+
+	play, _ := providers.NewCassette(providers.CassetteReplay, dir)
+	offline := providers.NewGeminiProvider(providers.Settings{APIKey: "other", BaseURL: srv.URL, Transport: play})
+	got, err := offline.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if got != want || calls != 1 {
+		t.Errorf("replay = %+v (calls=%d), want %+v", got, calls, want)
+	}
+
+	if _, err := offline.Generate(context.Background(), models.GenerationRequest{Prompt: "never recorded"}); err == nil {
+		t.Error("expected an error for an unrecorded request")
+	}
+}
+
+func TestCassette_RedactsTokensInResponses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"token":"tid=live-session-secret","expires_at":1700000000}`)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	rec, _ := providers.NewCassette(providers.CassetteRecord, dir)
+	resp, err := (&http.Client{Transport: rec}).Get(srv.URL + "/copilot_internal/v2/token")
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	live, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(live), "live-session-secret") {
+		t.Errorf("the live caller must still get the token, got %s", live)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("expected one recorded interaction, got %d", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	if strings.Contains(string(data), "live-session-secret") || !strings.Contains(string(data), "expires_at") {
+		t.Errorf("recorded token exchange = %s, want the token redacted", data)
+	}
+}
@@ -0,0 +1,110 @@
+package providers
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// catalogFetchTimeout bounds a single model listing call.
+const catalogFetchTimeout = 10 * time.Second
+
+// modelCatalog caches a provider's discovered model list. Lookups never block
+// on the network: until the first listing succeeds, and whenever listing
+// fails, the configured models are returned; a stale list is refreshed in the
+// background.
+type modelCatalog struct {
+	provider   string
+	configured []ModelInfo
+	ttl        time.Duration
+	fetch      func(ctx context.Context) ([]ModelInfo, error)
+	now        func() time.Time
+
+	mu         sync.Mutex
+	discovered []ModelInfo
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+// newModelCatalog returns a catalog for provider. With a zero ttl, or no
+// fetch function, discovery is disabled and the configured list is used.
+func newModelCatalog(provider string, s Settings, fetch func(ctx context.Context) ([]ModelInfo, error)) *modelCatalog {
+	if s.ModelsTTL <= 0 {
+		fetch = nil
+	}
+	return &modelCatalog{provider: provider, configured: s.Models, ttl: s.ModelsTTL, fetch: fetch, now: time.Now}
+}
+
+// models returns the current model list.
+func (c *modelCatalog) models() []ModelInfo {
+	if c.fetch == nil {
+		return c.configured
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.refreshing && c.now().Sub(c.fetchedAt) >= c.ttl {
+		c.refreshing = true
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), catalogFetchTimeout)
+			defer cancel()
+			c.refresh(ctx)
+		}()
+	}
+	if c.discovered == nil {
+		return c.configured
+	}
+	return c.discovered
+}
+
+// refresh lists the provider's models and merges them with the configured
+// metadata. A failure keeps the previous list and is retried after ttl.
+func (c *modelCatalog) refresh(ctx context.Context) {
+	found, err := c.fetch(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshing = false
+	c.fetchedAt = c.now()
+	if err != nil {
+		log.Printf("[ai-service] %s: model discovery failed, using configured models: %v", c.provider, err)
+		return
+	}
+	if len(found) == 0 {
+		return
+	}
+	c.discovered = mergeModels(c.provider, found, c.configured)
+}
+
+// mergeModels returns the discovered models in discovery order, with display
+// names and capabilities from the configured entry of the same ID taking
+// precedence over what the vendor reported.
+func mergeModels(provider string, discovered, configured []ModelInfo) []ModelInfo {
+	byID := make(map[string]ModelInfo, len(configured))
+	for _, m := range configured {
+		byID[m.ID] = m
+	}
+	out := make([]ModelInfo, 0, len(discovered))
+	for _, m := range discovered {
+		m.Provider = provider
+		if cfg, ok := byID[m.ID]; ok {
+			// Config fills an omitted display name with the ID, which
+			// should not hide the vendor's name.
+			if cfg.DisplayName != "" && cfg.DisplayName != cfg.ID {
+				m.DisplayName = cfg.DisplayName
+			}
+			if cfg.ContextWindow > 0 {
+				m.ContextWindow = cfg.ContextWindow
+			}
+			if cfg.MaxOutputTokens > 0 {
+				m.MaxOutputTokens = cfg.MaxOutputTokens
+			}
+			m.Vision = m.Vision || cfg.Vision
+		}
+		if m.DisplayName == "" {
+			m.DisplayName = m.ID
+		}
+		out = append(out, m)
+	}
+	return out
+}
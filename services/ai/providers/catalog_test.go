@@ -0,0 +1,60 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestModelCatalog_MergesAndFallsBack(t *testing.T) {
+	configured := []ModelInfo{
+		{ID: "m-1", DisplayName: "Model One", Provider: "p", Vision: true},
+		{ID: "m-retired", DisplayName: "Retired", Provider: "p"},
+	}
+	var fail bool
+	fetch := func(context.Context) ([]ModelInfo, error) {
+		if fail {
+			return nil, errors.New("unavailable")
+		}
+		return []ModelInfo{
+			{ID: "m-1", DisplayName: "vendor name", ContextWindow: 1000},
+			{ID: "m-2", DisplayName: "Model Two"},
+		}, nil
+	}
+	now := time.Unix(0, 0)
+	c := newModelCatalog("p", Settings{Models: configured, ModelsTTL: time.Hour}, fetch)
+	c.now = func() time.Time { return now }
+	c.refreshing = true // drive refreshes by hand
+
+	if got := c.models(); len(got) != 2 || got[1].ID != "m-retired" {
+		t.Fatalf("before discovery = %+v, want configured list", got)
+	}
+
+	c.refresh(context.Background())
+	got := c.models()
+	want := []ModelInfo{
+		{ID: "m-1", DisplayName: "Model One", Provider: "p", ContextWindow: 1000, Vision: true},
+		{ID: "m-2", DisplayName: "Model Two", Provider: "p"},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("after discovery = %+v, want %+v", got, want)
+	}
+
+	fail = true
+	c.refresh(context.Background())
+	if got := c.models(); len(got) != 2 || got[1].ID != "m-2" {
+		t.Errorf("a failed refresh should keep the last discovered list, got %+v", got)
+	}
+}
+
+func TestModelCatalog_DisabledWithoutTTL(t *testing.T) {
+	called := false
+	c := newModelCatalog("p", Settings{Models: []ModelInfo{{ID: "x"}}}, func(context.Context) ([]ModelInfo, error) {
+		called = true
+		return nil, nil
+	})
+	if got := c.models(); len(got) != 1 || called {
+		t.Errorf("models = %+v, fetch called = %v", got, called)
+	}
+}
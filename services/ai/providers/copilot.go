@@ -1,18 +1,12 @@
 package providers
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"strings"
 	"sync"
 	"time"
-
-	"github.com/zest-app/ai-service/models"
 )
 
 const copilotSystemPrompt = `You are an expert HTML/CSS developer.
@@ -31,47 +25,57 @@ func (t *copilotSessionToken) valid() bool {
 	return t != nil && t.token != "" && time.Now().Before(t.expiresAt.Add(-30*time.Second))
 }
 
-// CopilotProvider calls GitHub Copilot via the OpenAI-compatible API.
-// It accepts a GitHub OAuth token (gho_...) and exchanges it automatically
-// for a short-lived Copilot session token before each request.
-type CopilotProvider struct {
-	oauthToken   string
-	baseURL      string
-	model        string
-	client       *http.Client
-	sessionMu    sync.Mutex
-	sessionToken *copilotSessionToken
+// copilotDefaults are used for any Settings field left empty.
+var copilotDefaults = Settings{
+	BaseURL:      "https://api.githubcopilot.com",
+	DefaultModel: "gpt-4o",
+	Models: []ModelInfo{
+		{ID: "gpt-4o", DisplayName: "GPT-4o", Provider: "copilot"},
+		{ID: "gpt-4o-mini", DisplayName: "GPT-4o Mini", Provider: "copilot"},
+		{ID: "claude-3.5-sonnet", DisplayName: "Claude 3.5 Sonnet", Provider: "copilot"},
+	},
+	Timeout: 30 * time.Second,
 }
 
-// NewCopilotProvider creates a CopilotProvider. Returns an instance regardless;
-// Enabled() returns false when GITHUB_COPILOT_TOKEN is not set.
-func NewCopilotProvider() *CopilotProvider {
-	return &CopilotProvider{
-		oauthToken: os.Getenv("GITHUB_COPILOT_TOKEN"),
-		baseURL:    "https://api.githubcopilot.com",
-		model:      "gpt-4o",
-		client:     &http.Client{Timeout: 30 * time.Second},
-	}
+// copilotHeaders identify the client to the Copilot API.
+var copilotHeaders = map[string]string{
+	"Copilot-Integration-Id": "vscode-chat",
+	"Editor-Version":         "vscode/1.85.0",
+	"Editor-Plugin-Version":  "copilot-chat/0.12.0",
+	"User-Agent":             "GithubCopilot/1.155.0",
 }
 
-func (p *CopilotProvider) Name() string  { return "copilot" }
-func (p *CopilotProvider) Enabled() bool { return p.oauthToken != "" }
+// CopilotProvider calls GitHub Copilot via the OpenAI-compatible API.
+// It accepts a GitHub OAuth token (gho_...) as its API key and exchanges it
+// automatically for a short-lived Copilot session token before each request.
+// Session tokens are cached per OAuth token, so each pooled key keeps its own.
+type CopilotProvider struct {
+	*OpenAICompatibleProvider
+	sessionMu     sync.Mutex
+	sessionTokens map[string]*copilotSessionToken
+}
 
-func (p *CopilotProvider) Models() []ModelInfo {
-	return []ModelInfo{
-		{ID: "gpt-4o", DisplayName: "GPT-4o", Provider: "copilot"},
-		{ID: "gpt-4o-mini", DisplayName: "GPT-4o Mini", Provider: "copilot"},
-		{ID: "claude-3.5-sonnet", DisplayName: "Claude 3.5 Sonnet", Provider: "copilot"},
-	}
+// NewCopilotProvider creates a CopilotProvider. Returns an instance regardless;
+// Enabled() returns false when no OAuth token is configured.
+func NewCopilotProvider(s Settings) *CopilotProvider {
+	p := &CopilotProvider{OpenAICompatibleProvider: NewOpenAICompatibleProvider(OpenAICompatibleConfig{
+		Name:         "copilot",
+		Settings:     s.withDefaults(copilotDefaults),
+		Headers:      copilotHeaders,
+		SystemPrompt: copilotSystemPrompt,
+	}), sessionTokens: make(map[string]*copilotSessionToken)}
+	p.token = p.getSessionToken
+	return p
 }
 
-// getSessionToken returns a valid Copilot session token, refreshing it if expired.
-func (p *CopilotProvider) getSessionToken(ctx context.Context) (string, error) {
+// getSessionToken returns a valid Copilot session token for the OAuth token
+// oauth, refreshing it if expired.
+func (p *CopilotProvider) getSessionToken(ctx context.Context, oauth string) (string, error) {
 	p.sessionMu.Lock()
 	defer p.sessionMu.Unlock()
 
-	if p.sessionToken.valid() {
-		return p.sessionToken.token, nil
+	if t := p.sessionTokens[oauth]; t.valid() {
+		return t.token, nil
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
@@ -79,22 +83,22 @@ func (p *CopilotProvider) getSessionToken(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("copilot: build token request: %w", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+p.oauthToken)
+	req.Header.Set("Authorization", "Bearer "+oauth)
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Editor-Version", "vscode/1.85.0")
-	req.Header.Set("Editor-Plugin-Version", "copilot-chat/0.12.0")
-	req.Header.Set("User-Agent", "GithubCopilot/1.155.0")
+	req.Header.Set("Editor-Version", copilotHeaders["Editor-Version"])
+	req.Header.Set("Editor-Plugin-Version", copilotHeaders["Editor-Plugin-Version"])
+	req.Header.Set("User-Agent", copilotHeaders["User-Agent"])
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("copilot: token request: %w", err)
+		return "", transportError("copilot", "token request", err)
 	}
-	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("copilot: token exchange status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+		e := statusError("copilot", resp)
+		e.Message = "token exchange: " + e.Message
+		return "", e
 	}
+	defer resp.Body.Close()
 
 	var result struct {
 		Token     string `json:"token"`
@@ -107,61 +111,9 @@ func (p *CopilotProvider) getSessionToken(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("copilot: empty token in response")
 	}
 
-	p.sessionToken = &copilotSessionToken{
+	p.sessionTokens[oauth] = &copilotSessionToken{
 		token:     result.Token,
 		expiresAt: time.Unix(result.ExpiresAt, 0),
 	}
-	return p.sessionToken.token, nil
-}
-
-func (p *CopilotProvider) Generate(ctx context.Context, req models.GenerationRequest) (string, error) {
-	sessionToken, err := p.getSessionToken(ctx)
-	if err != nil {
-		return "", err
-	}
-
-	model := p.model
-	if req.PreferredModel != "" && req.PreferredProvider == p.Name() {
-		model = req.PreferredModel
-	}
-
-	payload := map[string]any{
-		"model": model,
-		"messages": []map[string]string{
-			{"role": "system", "content": getSystemPrompt(req, copilotSystemPrompt)},
-			{"role": "user", "content": buildUserPrompt(req)},
-		},
-		"temperature": 0.7,
-		"max_tokens":  8192,
-	}
-
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("copilot: marshal: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost,
-		p.baseURL+"/chat/completions", bytes.NewReader(body))
-	if err != nil {
-		return "", fmt.Errorf("copilot: new request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+sessionToken)
-	httpReq.Header.Set("Copilot-Integration-Id", "vscode-chat")
-	httpReq.Header.Set("Editor-Version", "vscode/1.85.0")
-	httpReq.Header.Set("Editor-Plugin-Version", "copilot-chat/0.12.0")
-	httpReq.Header.Set("User-Agent", "GithubCopilot/1.155.0")
-
-	resp, err := p.client.Do(httpReq)
-	if err != nil {
-		return "", fmt.Errorf("copilot: do request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("copilot: status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
-	}
-
-	return extractOpenAIContent(resp.Body)
+	return result.Token, nil
 }
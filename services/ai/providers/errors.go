@@ -0,0 +1,117 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorKind classifies a provider failure so the router can decide whether to
+// retry the same provider, move to the next one, or give up.
+type ErrorKind string
+
+const (
+	ErrRateLimited ErrorKind = "rate_limited" // 429
+	ErrServer      ErrorKind = "server_error" // 5xx
+	ErrAuth        ErrorKind = "auth_failure" // 401, 403
+	ErrBadRequest  ErrorKind = "bad_request"  // other 4xx
+	ErrTimeout     ErrorKind = "timeout"      // 408, network or deadline timeout
+	ErrTransport   ErrorKind = "transport"    // connection refused, reset, DNS…
+)
+
+// Error is a classified provider failure.
+type Error struct {
+	Provider   string
+	Kind       ErrorKind
+	StatusCode int // 0 for transport errors
+	// RetryAfter is the delay requested by the provider's Retry-After header,
+	// or zero when none was sent.
+	RetryAfter time.Duration
+	Message    string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: status %d: %s", e.Provider, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Provider, e.Message)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Retryable reports whether the same provider may succeed if asked again.
+func (e *Error) Retryable() bool {
+	switch e.Kind {
+	case ErrRateLimited, ErrServer, ErrTimeout, ErrTransport:
+		return true
+	}
+	return false
+}
+
+// statusError reads and closes a non-200 response body and classifies it.
+func statusError(provider string, resp *http.Response) *Error {
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	e := &Error{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Message:    strings.TrimSpace(string(b)),
+	}
+	switch code := resp.StatusCode; {
+	case code == http.StatusTooManyRequests:
+		e.Kind = ErrRateLimited
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		e.Kind = ErrAuth
+	case code == http.StatusRequestTimeout:
+		e.Kind = ErrTimeout
+	case code >= 500:
+		e.Kind = ErrServer
+	default:
+		e.Kind = ErrBadRequest
+	}
+	return e
+}
+
+// transportError classifies an error returned by http.Client.Do or while
+// reading a response body.
+func transportError(provider, op string, err error) *Error {
+	kind := ErrTransport
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		kind = ErrTimeout
+	}
+	return &Error{
+		Provider: provider,
+		Kind:     kind,
+		Message:  fmt.Sprintf("%s: %v", op, err),
+		Err:      err,
+	}
+}
+
+// parseRetryAfter parses a Retry-After header in either delay-seconds or
+// HTTP-date form. It returns zero when the header is absent or invalid.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package providers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusError_Classification(t *testing.T) {
+	tests := []struct {
+		code      int
+		kind      ErrorKind
+		retryable bool
+	}{
+		{http.StatusTooManyRequests, ErrRateLimited, true},
+		{http.StatusServiceUnavailable, ErrServer, true},
+		{http.StatusInternalServerError, ErrServer, true},
+		{http.StatusRequestTimeout, ErrTimeout, true},
+		{http.StatusUnauthorized, ErrAuth, false},
+		{http.StatusForbidden, ErrAuth, false},
+		{http.StatusBadRequest, ErrBadRequest, false},
+		{http.StatusNotFound, ErrBadRequest, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{
+			StatusCode: tt.code,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(" oops \n")),
+		}
+		e := statusError("glm", resp)
+		if e.Kind != tt.kind || e.Retryable() != tt.retryable {
+			t.Errorf("status %d: kind=%s retryable=%v, want %s/%v", tt.code, e.Kind, e.Retryable(), tt.kind, tt.retryable)
+		}
+		if !strings.HasPrefix(e.Error(), "glm: status ") || !strings.HasSuffix(e.Error(), ": oops") {
+			t.Errorf("unexpected message %q", e.Error())
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(3 * time.Second).Format(http.TimeFormat), 3 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.in, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package providers
+
+import (
+	"errors"
+	"strings"
+)
+
+// maxContinuations caps how many follow-up calls are made to finish a page
+// that was cut off at the provider's output token limit. Continuations are
+// part of the same attempt and do not count against BR-006.
+const maxContinuations = 2
+
+// continuationInstruction is sent as the user turn after the partial output.
+const continuationInstruction = `Your previous response was cut off because it reached the output length limit.
+Continue EXACTLY where it stopped — do not repeat any earlier content, do not restart the document,
+and do not add explanations or code fences. Output only the remaining HTML.`
+
+// ErrContentFiltered is returned when the provider refused to generate because
+// of its own safety filters. The router does not fall back to another
+// provider in this case; handlers report it with the "moderated" status.
+var ErrContentFiltered = errors.New("content filtered by provider")
+
+// truncatedFinishReasons mark output that stopped at the token limit.
+var truncatedFinishReasons = map[string]bool{
+	"length":     true, // OpenAI-compatible
+	"MAX_TOKENS": true, // Gemini
+	"max_tokens": true, // Anthropic
+}
+
+// blockedFinishReasons mark output withheld by a provider safety filter.
+var blockedFinishReasons = map[string]bool{
+	"content_filter":     true, // OpenAI-compatible
+	"sensitive":          true, // GLM
+	"SAFETY":             true, // Gemini
+	"PROHIBITED_CONTENT": true,
+	"BLOCKLIST":          true,
+	"SPII":               true,
+	"refusal":            true, // Anthropic
+}
+
+// Truncated reports whether the provider stopped because it ran out of output
+// tokens, i.e. the page is incomplete.
+func (r Response) Truncated() bool {
+	return truncatedFinishReasons[r.FinishReason]
+}
+
+// Blocked reports whether the provider withheld output for safety reasons.
+func (r Response) Blocked() bool {
+	return blockedFinishReasons[r.FinishReason]
+}
+
+// appendContinuation stitches a continuation response onto r. Token usage is
+// summed; finish reason and model come from the latest call.
+func (r Response) appendContinuation(next Response) Response {
+	r.Text = stitch(r.Text, next.Text)
+	r.PromptTokens += next.PromptTokens
+	r.CompletionTokens += next.CompletionTokens
+	r.FinishReason = next.FinishReason
+	if next.Model != "" {
+		r.Model = next.Model
+	}
+	return r
+}
+
+// maxStitchOverlap bounds the search for text repeated at the seam.
+const maxStitchOverlap = 256
+
+// stitch joins a truncated response and its continuation. Models often open a
+// continuation with a code fence or repeat the last few characters they
+// produced, so both are removed before joining.
+func stitch(prev, next string) string {
+	next = strings.TrimLeft(next, "\r\n")
+	if strings.HasPrefix(next, "```") {
+		if i := strings.IndexByte(next, '\n'); i >= 0 {
+			next = next[i+1:]
+		}
+	}
+	if trimmed := strings.TrimRight(next, " \r\n"); strings.HasSuffix(trimmed, "```") {
+		next = strings.TrimRight(strings.TrimSuffix(trimmed, "```"), " \r\n")
+	}
+
+	limit := maxStitchOverlap
+	if len(prev) < limit {
+		limit = len(prev)
+	}
+	if len(next) < limit {
+		limit = len(next)
+	}
+	for n := limit; n >= 8; n-- {
+		if strings.HasSuffix(prev, next[:n]) {
+			return prev + next[n:]
+		}
+	}
+	return prev + next
+}
@@ -0,0 +1,21 @@
+package providers
+
+import "testing"
+
+func TestStitch(t *testing.T) {
+	tests := []struct {
+		name, prev, next, want string
+	}{
+		{"plain", "<div><p>Hel", "lo</p></div>", "<div><p>Hello</p></div>"},
+		{"code fence", "<div><p>Hel", "```html\nlo</p></div>\n```", "<div><p>Hello</p></div>"},
+		{"repeated seam", "<section><h2>Pricing plans", "<h2>Pricing plans</h2></section>", "<section><h2>Pricing plans</h2></section>"},
+		{"short overlap ignored", "<p>a", "a</p>", "<p>aa</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stitch(tt.prev, tt.next); got != tt.want {
+				t.Errorf("stitch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,7 +7,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	neturl "net/url"
+	"slices"
 	"strings"
 
 	"github.com/zest-app/ai-service/models"
@@ -19,101 +20,256 @@ Output ONLY the raw HTML — no explanations, no markdown, no code fences.
 The HTML must include a <style> block inside <head> for all CSS.
 Use semantic HTML5 elements, responsive design, and modern CSS.`
 
+// geminiDefaults are used for any Settings field left empty.
+var geminiDefaults = Settings{
+	BaseURL:      "https://generativelanguage.googleapis.com/v1beta",
+	DefaultModel: "gemini-2.5-flash",
+	Models: []ModelInfo{
+		{ID: "gemini-2.5-flash", DisplayName: "Gemini 2.5 Flash", Provider: "gemini"},
+		{ID: "gemini-2.5-pro", DisplayName: "Gemini 2.5 Pro", Provider: "gemini"},
+	},
+}
+
 // GeminiProvider calls Google Gemini via the generativelanguage REST API.
 type GeminiProvider struct {
-	apiKey string
-	model  string
-	client *http.Client
+	settings Settings
+	client   *http.Client
+	catalog  *modelCatalog
+	keys     *KeyPool
 }
 
-// NewGeminiProvider creates a GeminiProvider. Returns nil if GEMINI_API_KEY is not set.
-func NewGeminiProvider() *GeminiProvider {
-	return &GeminiProvider{
-		apiKey: os.Getenv("GEMINI_API_KEY"),
-		model:  "gemini-2.5-flash",
-		client: &http.Client{},
-	}
+// NewGeminiProvider creates a GeminiProvider. Enabled() returns false when no
+// API key is configured.
+func NewGeminiProvider(s Settings) *GeminiProvider {
+	s = s.withDefaults(geminiDefaults)
+	p := &GeminiProvider{settings: s, client: s.httpClient(), keys: s.keyPool()}
+	p.catalog = newModelCatalog("gemini", s, p.listModels)
+	return p
 }
 
 func (p *GeminiProvider) Name() string  { return "gemini" }
-func (p *GeminiProvider) Enabled() bool { return p.apiKey != "" }
+func (p *GeminiProvider) Enabled() bool { return p.keys.Len() > 0 }
 
-func (p *GeminiProvider) Models() []ModelInfo {
-	return []ModelInfo{
-		{ID: "gemini-2.5-flash", DisplayName: "Gemini 2.5 Flash", Provider: "gemini"},
-		{ID: "gemini-2.5-pro", DisplayName: "Gemini 2.5 Pro", Provider: "gemini"},
+// Models returns the models discovered from the models endpoint when
+// discovery is enabled, otherwise the configured list.
+func (p *GeminiProvider) Models() []ModelInfo { return p.catalog.models() }
+
+// KeyStatus reports usage of each pooled API key.
+func (p *GeminiProvider) KeyStatus() []KeyStatus { return p.keys.Status() }
+
+// listModels fetches the models supporting generateContent.
+func (p *GeminiProvider) listModels(ctx context.Context) ([]ModelInfo, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.settings.BaseURL+"/models?pageSize=1000", nil)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: new request: %w", err)
+	}
+	resp, err := p.send(httpReq, "list models")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list struct {
+		Models []struct {
+			Name                       string   `json:"name"` // "models/gemini-2.5-flash"
+			DisplayName                string   `json:"displayName"`
+			InputTokenLimit            int      `json:"inputTokenLimit"`
+			OutputTokenLimit           int      `json:"outputTokenLimit"`
+			SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("gemini: decode models: %w", err)
+	}
+	var out []ModelInfo
+	for _, m := range list.Models {
+		if !slices.Contains(m.SupportedGenerationMethods, "generateContent") {
+			continue
+		}
+		out = append(out, ModelInfo{
+			ID:              strings.TrimPrefix(m.Name, "models/"),
+			DisplayName:     m.DisplayName,
+			ContextWindow:   m.InputTokenLimit,
+			MaxOutputTokens: m.OutputTokenLimit,
+		})
+	}
+	return out, nil
+}
+
+func (p *GeminiProvider) Generate(ctx context.Context, req models.GenerationRequest) (Response, error) {
+	resp, err := p.doRequest(ctx, req, "generateContent")
+	if err != nil {
+		return Response{}, err
 	}
+	defer resp.Body.Close()
+
+	return extractGeminiContent(resp.Body)
 }
 
-func (p *GeminiProvider) Generate(ctx context.Context, req models.GenerationRequest) (string, error) {
-	model := p.model
-	if req.PreferredModel != "" && req.PreferredProvider == p.Name() {
-		model = req.PreferredModel
+// GenerateStream implements StreamingProvider via streamGenerateContent with
+// alt=sse, which returns one GenerateContentResponse per event.
+func (p *GeminiProvider) GenerateStream(ctx context.Context, req models.GenerationRequest, onChunk func(string) error) (Response, error) {
+	resp, err := p.doRequest(ctx, req, "streamGenerateContent")
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	return streamGeminiContent(resp.Body, onChunk)
+}
+
+// doRequest calls the given model method ("generateContent" or
+// "streamGenerateContent") and returns the response once a 200 status has
+// been received. The caller must close the body.
+func (p *GeminiProvider) doRequest(ctx context.Context, req models.GenerationRequest, method string) (*http.Response, error) {
+	model := p.settings.model(req, p.Name())
+	temperature, maxTokens := p.settings.params(model)
+	url := fmt.Sprintf("%s/models/%s:%s", p.settings.BaseURL, neturl.PathEscape(model), method)
+	if method == "streamGenerateContent" {
+		url += "?alt=sse"
 	}
-	url := fmt.Sprintf(
-		"https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s",
-		model, p.apiKey,
-	)
 
 	payload := map[string]any{
 		"system_instruction": map[string]any{
 			"parts": []map[string]string{{"text": getSystemPrompt(req, geminiSystemPrompt)}},
 		},
-		"contents": []map[string]any{
-			{
-				"role": "user",
-				"parts": []map[string]string{
-					{"text": buildUserPrompt(req)},
-				},
-			},
-		},
+		"contents": geminiContents(req),
 		"generationConfig": map[string]any{
-			"temperature":     0.7,
-			"maxOutputTokens": 8192,
+			"temperature":     temperature,
+			"maxOutputTokens": maxTokens,
 		},
 	}
 
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("gemini: marshal: %w", err)
+		return nil, fmt.Errorf("gemini: marshal: %w", err)
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("gemini: new request: %w", err)
+		return nil, fmt.Errorf("gemini: new request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := p.client.Do(httpReq)
-	if err != nil {
-		return "", fmt.Errorf("gemini: do request: %w", err)
-	}
-	defer resp.Body.Close()
+	return p.send(httpReq, "do request")
+}
 
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("gemini: status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+// geminiContents builds the conversation turns for a request, replaying the
+// partial output as a model turn when asking for a continuation.
+func geminiContents(req models.GenerationRequest) []map[string]any {
+	turn := func(role, text string) map[string]any {
+		return map[string]any{"role": role, "parts": []map[string]string{{"text": text}}}
 	}
+	contents := []map[string]any{turn("user", buildUserPrompt(req))}
+	if req.Context.PartialOutput != "" {
+		contents = append(contents,
+			turn("model", req.Context.PartialOutput),
+			turn("user", continuationInstruction),
+		)
+	}
+	return contents
+}
 
-	return extractGeminiContent(resp.Body)
+// geminiResponse is the shape of a (streamed or unary) Gemini response.
+// When streaming, usageMetadata is cumulative, so the last chunk's value wins.
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"content"`
+		FinishReason string `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion   string `json:"modelVersion"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
 }
 
-// extractGeminiContent parses the Gemini response format.
-func extractGeminiContent(r io.Reader) (string, error) {
-	var result struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
+// merge copies the metadata of one response (or stream chunk) into resp and
+// returns the candidate text it carries.
+func (g geminiResponse) merge(resp *Response) string {
+	if g.ModelVersion != "" {
+		resp.Model = g.ModelVersion
+	}
+	if g.UsageMetadata != nil {
+		resp.PromptTokens = g.UsageMetadata.PromptTokenCount
+		resp.CompletionTokens = g.UsageMetadata.CandidatesTokenCount
+	}
+	if len(g.Candidates) == 0 {
+		return ""
+	}
+	if fr := g.Candidates[0].FinishReason; fr != "" {
+		resp.FinishReason = fr
 	}
+	var sb strings.Builder
+	for _, part := range g.Candidates[0].Content.Parts {
+		sb.WriteString(part.Text)
+	}
+	return sb.String()
+}
+
+// extractGeminiContent parses the Gemini response format.
+func extractGeminiContent(r io.Reader) (Response, error) {
+	var result geminiResponse
 	if err := json.NewDecoder(r).Decode(&result); err != nil {
-		return "", fmt.Errorf("gemini: decode response: %w", err)
+		return Response{}, fmt.Errorf("gemini: decode response: %w", err)
 	}
-	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("gemini: empty candidates in response")
+	if result.PromptFeedback != nil && result.PromptFeedback.BlockReason != "" {
+		return Response{FinishReason: result.PromptFeedback.BlockReason},
+			fmt.Errorf("gemini: prompt blocked (%s): %w", result.PromptFeedback.BlockReason, ErrContentFiltered)
 	}
-	return result.Candidates[0].Content.Parts[0].Text, nil
+	if len(result.Candidates) == 0 {
+		return Response{}, fmt.Errorf("gemini: empty candidates in response")
+	}
+	var resp Response
+	resp.Text = result.merge(&resp)
+	if resp.Text == "" && !resp.Blocked() {
+		return Response{}, fmt.Errorf("gemini: empty candidates in response")
+	}
+	return resp, nil
+}
+
+// streamGeminiContent reads a streamGenerateContent SSE body, forwarding the
+// text of each chunk to onChunk, and returns the full text.
+func streamGeminiContent(r io.Reader, onChunk func(string) error) (Response, error) {
+	var resp Response
+	var sb strings.Builder
+	err := readSSE(r, func(data string) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("gemini: decode stream chunk: %w", err)
+		}
+		if chunk.PromptFeedback != nil && chunk.PromptFeedback.BlockReason != "" {
+			resp.FinishReason = chunk.PromptFeedback.BlockReason
+			return fmt.Errorf("gemini: prompt blocked (%s): %w", chunk.PromptFeedback.BlockReason, ErrContentFiltered)
+		}
+		text := chunk.merge(&resp)
+		if text == "" {
+			return nil
+		}
+		sb.WriteString(text)
+		return onChunk(text)
+	})
+	if err != nil {
+		return resp, err
+	}
+	if sb.Len() == 0 && !resp.Blocked() {
+		return Response{}, fmt.Errorf("gemini: empty stream")
+	}
+	resp.Text = sb.String()
+	return resp, nil
+}
+
+// send attaches a pooled API key to httpReq and sends it; see sendWithKey.
+func (p *GeminiProvider) send(httpReq *http.Request, op string) (*http.Response, error) {
+	return sendWithKey(p.client, p.keys, "gemini", op, httpReq, func(r *http.Request, key string) error {
+		r.Header.Set("x-goog-api-key", key)
+		return nil
+	})
 }
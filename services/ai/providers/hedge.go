@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+)
+
+// hedgeOutcome is the result of one concurrent attempt.
+type hedgeOutcome struct {
+	resp Response
+	err  error
+}
+
+// routeHedged is Route with hedging: each time delay passes without an
+// answer, the next candidate is started alongside the ones still running. A
+// failure starts the next candidate immediately, as in sequential fallback.
+// The first valid response wins and the other attempts are cancelled through
+// their contexts, which their breakers treat as abandoned.
+func (r *Router) routeHedged(ctx context.Context, req models.GenerationRequest, c *candidates, delay time.Duration) (Response, error) {
+	results := make(chan hedgeOutcome, maxFallbackAttempts)
+	var cancels []context.CancelFunc
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	running := 0
+	launch := func() bool {
+		p, breaker, ok := c.next()
+		if !ok {
+			return false
+		}
+		actx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		running++
+		go func() {
+			resp, err := r.try(actx, p, breaker, req, nil)
+			results <- hedgeOutcome{resp, err}
+		}()
+		return true
+	}
+
+	if !launch() {
+		return Response{}, c.failure(nil)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var errs []string
+	for running > 0 {
+		select {
+		case out := <-results:
+			running--
+			if out.err == nil || errors.Is(out.err, ErrContentFiltered) {
+				return out.resp, out.err
+			}
+			errs = append(errs, out.err.Error())
+			if ctx.Err() == nil {
+				launch()
+			}
+		case <-timer.C:
+			if launch() {
+				log.Printf("[ai-service] hedging after %s: attempt %d started", delay, c.attempts)
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			errs = append(errs, ctx.Err().Error())
+			return Response{}, c.failure(errs)
+		}
+	}
+	return Response{}, c.failure(errs)
+}
@@ -0,0 +1,93 @@
+package providers_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/providers"
+)
+
+// slowProvider answers after delay unless its context is cancelled first.
+type slowProvider struct {
+	name      string
+	delay     time.Duration
+	calls     atomic.Int32
+	cancelled atomic.Bool
+}
+
+func (s *slowProvider) Name() string                  { return s.name }
+func (s *slowProvider) Enabled() bool                 { return true }
+func (s *slowProvider) Models() []providers.ModelInfo { return nil }
+
+func (s *slowProvider) Generate(ctx context.Context, req models.GenerationRequest) (providers.Response, error) {
+	s.calls.Add(1)
+	select {
+	case <-time.After(s.delay):
+		return providers.Response{Text: "<html>" + s.name + "</html>"}, nil
+	case <-ctx.Done():
+		s.cancelled.Store(true)
+		return providers.Response{}, ctx.Err()
+	}
+}
+
+func TestRoute_HedgesSlowProvider(t *testing.T) {
+	slow := &slowProvider{name: "a", delay: 5 * time.Second}
+	fast := &slowProvider{name: "b", delay: 10 * time.Millisecond}
+	r := providers.NewRouter(slow, fast).WithHedgeDelay(20 * time.Millisecond)
+
+	start := time.Now()
+	resp, err := r.Route(context.Background(), models.GenerationRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Provider != "b" || resp.Text != "<html>b</html>" {
+		t.Errorf("got provider %q text %q, want the hedge to win", resp.Provider, resp.Text)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("hedged request took %s", time.Since(start))
+	}
+	for deadline := time.Now().Add(time.Second); !slow.cancelled.Load() && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if !slow.cancelled.Load() {
+		t.Error("expected the losing attempt to be cancelled")
+	}
+}
+
+func TestRoute_HedgesCountAgainstAttempts(t *testing.T) {
+	var ps []providers.Provider
+	var all []*slowProvider
+	for _, name := range []string{"a", "b", "c", "d"} {
+		p := &slowProvider{name: name, delay: 200 * time.Millisecond}
+		ps = append(ps, p)
+		all = append(all, p)
+	}
+	r := providers.NewRouter(ps...).WithHedgeDelay(10 * time.Millisecond)
+
+	resp, err := r.Route(context.Background(), models.GenerationRequest{})
+	if err != nil || resp.Provider != "a" {
+		t.Fatalf("got provider %q, err %v; want the first attempt to win", resp.Provider, err)
+	}
+	if all[3].calls.Load() != 0 {
+		t.Error("expected BR-006 to cap hedging at 3 attempts")
+	}
+	if all[2].calls.Load() != 1 {
+		t.Error("expected a third attempt to be hedged")
+	}
+}
+
+func TestRoute_NoHedgeWhenFirstIsFast(t *testing.T) {
+	first := &slowProvider{name: "a", delay: time.Millisecond}
+	second := &slowProvider{name: "b", delay: time.Millisecond}
+	r := providers.NewRouter(first, second).WithHedgeDelay(time.Second)
+
+	if resp, err := r.Route(context.Background(), models.GenerationRequest{}); err != nil || resp.Provider != "a" {
+		t.Fatalf("got %q, %v", resp.Provider, err)
+	}
+	if second.calls.Load() != 0 {
+		t.Error("hedge started although the first provider answered in time")
+	}
+}
@@ -1,7 +1,9 @@
 package providers
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -42,23 +44,160 @@ func getSystemPrompt(req models.GenerationRequest, defaultPrompt string) string
 	return defaultPrompt
 }
 
+// chatMessages builds the OpenAI-compatible message list for a request. When
+// the router is asking for a continuation the partial output is replayed as
+// the assistant turn followed by continuationInstruction.
+func chatMessages(req models.GenerationRequest, defaultSystemPrompt string) []map[string]string {
+	messages := []map[string]string{
+		{"role": "system", "content": getSystemPrompt(req, defaultSystemPrompt)},
+		{"role": "user", "content": buildUserPrompt(req)},
+	}
+	if req.Context.PartialOutput != "" {
+		messages = append(messages,
+			map[string]string{"role": "assistant", "content": req.Context.PartialOutput},
+			map[string]string{"role": "user", "content": continuationInstruction},
+		)
+	}
+	return messages
+}
+
+// openAIUsage is the token usage block of an OpenAI-compatible response.
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
 // openAIChatResponse is the shape of an OpenAI-compatible chat completion response.
 type openAIChatResponse struct {
+	Model   string `json:"model"`
 	Choices []struct {
 		Message struct {
 			Content string `json:"content"`
 		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
 }
 
-// extractOpenAIContent decodes an OpenAI-compatible response and returns the first choice text.
-func extractOpenAIContent(r io.Reader) (string, error) {
+// extractOpenAIContent decodes an OpenAI-compatible response and returns the
+// first choice text together with usage metadata.
+func extractOpenAIContent(r io.Reader) (Response, error) {
 	var result openAIChatResponse
 	if err := json.NewDecoder(r).Decode(&result); err != nil {
-		return "", fmt.Errorf("decode openai response: %w", err)
+		return Response{}, fmt.Errorf("decode openai response: %w", err)
 	}
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("openai: empty choices in response")
+		return Response{}, fmt.Errorf("openai: empty choices in response")
+	}
+	resp := Response{
+		Text:         result.Choices[0].Message.Content,
+		FinishReason: result.Choices[0].FinishReason,
+		Model:        result.Model,
+	}
+	if result.Usage != nil {
+		resp.PromptTokens = result.Usage.PromptTokens
+		resp.CompletionTokens = result.Usage.CompletionTokens
+	}
+	return resp, nil
+}
+
+// errStreamDone is returned from readSSE callbacks to stop reading once the
+// provider has signalled the end of the stream.
+var errStreamDone = errors.New("stream done")
+
+// maxSSELineBytes bounds a single server-sent event line. Providers put a whole
+// JSON chunk on one data line, which can exceed bufio's 64KB default.
+const maxSSELineBytes = 1 << 20
+
+// readSSE reads a text/event-stream body and calls fn with the data payload of
+// each event. Multi-line data fields are joined with "\n" as per the spec.
+func readSSE(r io.Reader, fn func(data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineBytes)
+
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		payload := strings.Join(data, "\n")
+		data = data[:0]
+		return fn(payload)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if err := dispatch(); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "data:") {
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		// event:, id:, retry: and comment lines are not used by any provider.
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read event stream: %w", err)
+	}
+	return dispatch()
+}
+
+// openAIStreamChunk is the shape of one OpenAI-compatible streaming chunk.
+// With stream_options.include_usage the final chunk has no choices and carries
+// the usage block.
+type openAIStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+// streamOpenAIContent reads an OpenAI-compatible `stream: true` response,
+// forwarding each content delta to onChunk, and returns the full text with
+// the usage and finish reason reported along the way.
+func streamOpenAIContent(r io.Reader, onChunk func(string) error) (Response, error) {
+	var resp Response
+	var sb strings.Builder
+	err := readSSE(r, func(data string) error {
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("decode openai stream chunk: %w", err)
+		}
+		if chunk.Model != "" {
+			resp.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			resp.PromptTokens = chunk.Usage.PromptTokens
+			resp.CompletionTokens = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		if fr := chunk.Choices[0].FinishReason; fr != nil && *fr != "" {
+			resp.FinishReason = *fr
+		}
+		text := chunk.Choices[0].Delta.Content
+		if text == "" {
+			return nil
+		}
+		sb.WriteString(text)
+		return onChunk(text)
+	})
+	if err != nil && !errors.Is(err, errStreamDone) {
+		return Response{}, err
+	}
+	if sb.Len() == 0 && !resp.Blocked() {
+		return Response{}, fmt.Errorf("openai: empty stream")
 	}
-	return result.Choices[0].Message.Content, nil
+	resp.Text = sb.String()
+	return resp, nil
 }
@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractOpenAIContent_Usage(t *testing.T) {
+	body := `{
+		"model": "glm-4.5-air-0725",
+		"choices": [{"message": {"content": "<html></html>"}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 120, "completion_tokens": 800, "total_tokens": 920}
+	}`
+
+	resp, err := extractOpenAIContent(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Response{Text: "<html></html>", PromptTokens: 120, CompletionTokens: 800, FinishReason: "stop", Model: "glm-4.5-air-0725"}
+	if resp != want {
+		t.Errorf("got %+v, want %+v", resp, want)
+	}
+	if resp.TotalTokens() != 920 {
+		t.Errorf("TotalTokens() = %d, want 920", resp.TotalTokens())
+	}
+}
+
+func TestStreamOpenAIContent_UsageInFinalChunk(t *testing.T) {
+	body := `data: {"model":"gpt-4o","choices":[{"delta":{"content":"<html>"},"finish_reason":null}]}
+
+data: {"model":"gpt-4o","choices":[{"delta":{"content":"</html>"},"finish_reason":"stop"}]}
+
+data: {"model":"gpt-4o","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":4}}
+
+data: [DONE]
+
+`
+	var chunks []string
+	resp, err := streamOpenAIContent(strings.NewReader(body), func(s string) error {
+		chunks = append(chunks, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Response{Text: "<html></html>", PromptTokens: 10, CompletionTokens: 4, FinishReason: "stop", Model: "gpt-4o"}
+	if resp != want {
+		t.Errorf("got %+v, want %+v", resp, want)
+	}
+	if len(chunks) != 2 {
+		t.Errorf("expected 2 chunks, got %v", chunks)
+	}
+}
+
+func TestExtractGeminiContent_UsageMetadata(t *testing.T) {
+	body := `{
+		"candidates": [{"content": {"parts": [{"text": "<html>"}, {"text": "</html>"}]}, "finishReason": "STOP"}],
+		"usageMetadata": {"promptTokenCount": 50, "candidatesTokenCount": 700, "totalTokenCount": 750},
+		"modelVersion": "gemini-2.5-flash"
+	}`
+
+	resp, err := extractGeminiContent(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Response{Text: "<html></html>", PromptTokens: 50, CompletionTokens: 700, FinishReason: "STOP", Model: "gemini-2.5-flash"}
+	if resp != want {
+		t.Errorf("got %+v, want %+v", resp, want)
+	}
+}
+
+func TestStreamGeminiContent_CumulativeUsage(t *testing.T) {
+	body := `data: {"candidates":[{"content":{"parts":[{"text":"<html>"}]}}],"usageMetadata":{"promptTokenCount":50,"candidatesTokenCount":3}}
+
+data: {"candidates":[{"content":{"parts":[{"text":"</html>"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":50,"candidatesTokenCount":6},"modelVersion":"gemini-2.5-flash"}
+
+`
+	resp, err := streamGeminiContent(strings.NewReader(body), func(string) error { return nil })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Response{Text: "<html></html>", PromptTokens: 50, CompletionTokens: 6, FinishReason: "STOP", Model: "gemini-2.5-flash"}
+	if resp != want {
+		t.Errorf("got %+v, want %+v", resp, want)
+	}
+}
@@ -0,0 +1,247 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Key selection strategies for a KeyPool.
+const (
+	// KeyRoundRobin rotates through the keys in order.
+	KeyRoundRobin = "round_robin"
+	// KeyLeastUsed picks the key with the fewest requests in flight, then
+	// the fewest requests overall.
+	KeyLeastUsed = "least_used"
+)
+
+const (
+	// authQuarantine is how long a key rejected with 401/403 is set aside;
+	// it is probably revoked, so this is long, but not forever in case the
+	// vendor had a transient auth outage.
+	authQuarantine = 10 * time.Minute
+	// rateLimitQuarantine is used for a 429 without Retry-After.
+	rateLimitQuarantine = time.Minute
+)
+
+// KeyStatus is a snapshot of one pooled key, exposed through
+// GET /providers/stats. The key itself is never included.
+type KeyStatus struct {
+	// ID is the key's last four characters, enough to tell keys apart.
+	ID               string     `json:"id"`
+	Requests         int        `json:"requests"`
+	Failures         int        `json:"failures"`
+	InFlight         int        `json:"in_flight"`
+	QuarantinedUntil *time.Time `json:"quarantined_until,omitempty"`
+	LastError        string     `json:"last_error,omitempty"`
+}
+
+// KeyedProvider is implemented by providers that draw credentials from a
+// KeyPool.
+type KeyedProvider interface {
+	KeyStatus() []KeyStatus
+}
+
+type pooledKey struct {
+	value            string
+	requests         int
+	failures         int
+	inFlight         int
+	quarantinedUntil time.Time
+	lastError        string
+}
+
+// KeyPool hands out a provider's API keys, setting aside keys the vendor
+// rejected (401/403) or rate-limited (429) until they are likely usable again.
+type KeyPool struct {
+	mu       sync.Mutex
+	keys     []*pooledKey
+	strategy string
+	next     int
+	now      func() time.Time
+}
+
+// NewKeyPool creates a pool of the non-empty, distinct keys using strategy
+// (KeyRoundRobin when empty or unknown).
+func NewKeyPool(keys []string, strategy string) *KeyPool {
+	if strategy != KeyLeastUsed {
+		strategy = KeyRoundRobin
+	}
+	p := &KeyPool{strategy: strategy, now: time.Now}
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		p.keys = append(p.keys, &pooledKey{value: k})
+	}
+	return p
+}
+
+// Len returns the number of keys in the pool.
+func (p *KeyPool) Len() int { return len(p.keys) }
+
+// KeyLease is a key checked out for one request.
+type KeyLease struct {
+	pool *KeyPool
+	key  *pooledKey
+}
+
+// Key returns the API key to send.
+func (l *KeyLease) Key() string { return l.key.value }
+
+// Acquire checks out a key for provider. When every key is quarantined it
+// returns a rate-limit *Error whose RetryAfter is when the first key frees up,
+// so the router retries or falls back as for a vendor 429.
+func (p *KeyPool) Acquire(provider string) (*KeyLease, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.keys) == 0 {
+		return nil, &Error{Provider: provider, Kind: ErrAuth, Message: "no API key configured"}
+	}
+
+	now := p.now()
+	var pick *pooledKey
+	var soonest time.Time
+	for i := range p.keys {
+		k := p.keys[(p.next+i)%len(p.keys)]
+		if now.Before(k.quarantinedUntil) {
+			if soonest.IsZero() || k.quarantinedUntil.Before(soonest) {
+				soonest = k.quarantinedUntil
+			}
+			continue
+		}
+		if pick == nil {
+			pick = k
+			if p.strategy == KeyRoundRobin {
+				break
+			}
+			continue
+		}
+		if k.inFlight < pick.inFlight || k.inFlight == pick.inFlight && k.requests < pick.requests {
+			pick = k
+		}
+	}
+	if pick == nil {
+		return nil, &Error{
+			Provider:   provider,
+			Kind:       ErrRateLimited,
+			RetryAfter: soonest.Sub(now),
+			Message:    fmt.Sprintf("all %d API keys quarantined", len(p.keys)),
+		}
+	}
+	if p.strategy == KeyRoundRobin {
+		for i, k := range p.keys {
+			if k == pick {
+				p.next = (i + 1) % len(p.keys)
+			}
+		}
+	}
+	pick.requests++
+	pick.inFlight++
+	return &KeyLease{pool: p, key: pick}, nil
+}
+
+// Release returns the key with the outcome of the request it was used for.
+// Auth and rate-limit errors quarantine the key. Release on a nil lease is a
+// no-op.
+func (l *KeyLease) Release(err error) {
+	if l == nil {
+		return
+	}
+	p := l.pool
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	k := l.key
+	k.inFlight--
+	if err == nil {
+		return
+	}
+	k.failures++
+	k.lastError = err.Error()
+
+	var perr *Error
+	if !errors.As(err, &perr) {
+		return
+	}
+	switch perr.Kind {
+	case ErrAuth:
+		k.quarantinedUntil = p.now().Add(authQuarantine)
+	case ErrRateLimited:
+		d := perr.RetryAfter
+		if d <= 0 {
+			d = rateLimitQuarantine
+		}
+		k.quarantinedUntil = p.now().Add(d)
+	}
+}
+
+// Status returns a snapshot of every key in pool order.
+func (p *KeyPool) Status() []KeyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	out := make([]KeyStatus, 0, len(p.keys))
+	for _, k := range p.keys {
+		s := KeyStatus{
+			ID:        keyID(k.value),
+			Requests:  k.requests,
+			Failures:  k.failures,
+			InFlight:  k.inFlight,
+			LastError: k.lastError,
+		}
+		if now.Before(k.quarantinedUntil) {
+			until := k.quarantinedUntil
+			s.QuarantinedUntil = &until
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// keyID returns a short, non-secret identifier for key.
+func keyID(key string) string {
+	if len(key) <= 8 {
+		return "…"
+	}
+	return "…" + key[len(key)-4:]
+}
+
+// sendWithKey checks out a key, lets auth attach it to httpReq, sends the
+// request and returns the response once a 200 status has been received. The
+// key is released with the outcome; the caller must close the body. A nil
+// pool sends the request without credentials.
+func sendWithKey(client *http.Client, keys *KeyPool, provider, op string, httpReq *http.Request, auth func(*http.Request, string) error) (*http.Response, error) {
+	var lease *KeyLease
+	if keys != nil {
+		var err error
+		if lease, err = keys.Acquire(provider); err != nil {
+			return nil, err
+		}
+		if err := auth(httpReq, lease.Key()); err != nil {
+			lease.Release(err)
+			return nil, err
+		}
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		// A canceled request (e.g. a losing hedge) says nothing about the key.
+		if errors.Is(err, context.Canceled) {
+			lease.Release(nil)
+		} else {
+			lease.Release(err)
+		}
+		return nil, transportError(provider, op, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		e := statusError(provider, resp)
+		lease.Release(e)
+		return nil, e
+	}
+	lease.Release(nil)
+	return resp, nil
+}
@@ -0,0 +1,96 @@
+package providers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/providers"
+)
+
+func TestKeyPool_Selection(t *testing.T) {
+	rr := providers.NewKeyPool([]string{"a", "b", "a", "", "c"}, providers.KeyRoundRobin)
+	if rr.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3 distinct keys", rr.Len())
+	}
+	var got []string
+	for i := 0; i < 4; i++ {
+		l, err := rr.Acquire("p")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, l.Key())
+		l.Release(nil)
+	}
+	if want := "a,b,c,a"; strings.Join(got, ",") != want {
+		t.Errorf("round robin order = %v, want %s", got, want)
+	}
+
+	lu := providers.NewKeyPool([]string{"a", "b"}, providers.KeyLeastUsed)
+	held, _ := lu.Acquire("p")
+	next, _ := lu.Acquire("p")
+	if held.Key() != "a" || next.Key() != "b" {
+		t.Errorf("least used picked %s then %s, want a then b", held.Key(), next.Key())
+	}
+	next.Release(nil)
+	again, _ := lu.Acquire("p")
+	if again.Key() != "b" {
+		t.Errorf("least used picked %s while a is in flight, want b", again.Key())
+	}
+}
+
+func TestOpenAICompatible_QuarantinesRateLimitedKey(t *testing.T) {
+	const limited = "sk-limited-1111"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer "+limited {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		chatReply(w, false)
+	}))
+	defer srv.Close()
+	p := providers.NewOpenAICompatibleProvider(providers.OpenAICompatibleConfig{
+		Name:     "openai",
+		Settings: providers.Settings{APIKeys: []string{limited, "sk-healthy-2222"}, BaseURL: srv.URL + "/v1", DefaultModel: "gpt-4o"},
+	})
+	req := models.GenerationRequest{Prompt: "a page"}
+
+	_, err := p.Generate(context.Background(), req)
+	var perr *providers.Error
+	if !errors.As(err, &perr) || perr.Kind != providers.ErrRateLimited {
+		t.Fatalf("first call: err = %v, want rate limited", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := p.Generate(context.Background(), req); err != nil {
+			t.Fatalf("call %d: %v", i+2, err)
+		}
+	}
+
+	keys := p.KeyStatus()
+	if len(keys) != 2 {
+		t.Fatalf("KeyStatus() = %+v", keys)
+	}
+	if keys[0].ID != "…1111" || keys[0].Requests != 1 || keys[0].Failures != 1 || keys[0].QuarantinedUntil == nil {
+		t.Errorf("limited key = %+v, want one failed request and a quarantine", keys[0])
+	}
+	if keys[1].Requests != 2 || keys[1].Failures != 0 || keys[1].QuarantinedUntil != nil {
+		t.Errorf("healthy key = %+v, want both later requests", keys[1])
+	}
+}
+
+func TestKeyPool_AllQuarantinedIsRateLimited(t *testing.T) {
+	pool := providers.NewKeyPool([]string{"only"}, "")
+	l, _ := pool.Acquire("gemini")
+	l.Release(&providers.Error{Provider: "gemini", Kind: providers.ErrAuth, StatusCode: http.StatusUnauthorized})
+
+	_, err := pool.Acquire("gemini")
+	var perr *providers.Error
+	if !errors.As(err, &perr) || perr.Kind != providers.ErrRateLimited || perr.RetryAfter <= 0 {
+		t.Fatalf("err = %v, want rate limited with a retry delay", err)
+	}
+}
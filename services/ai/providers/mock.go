@@ -0,0 +1,149 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zest-app/ai-service/cache"
+	"github.com/zest-app/ai-service/models"
+)
+
+// mockChunkSize is how many bytes MockProvider streams per chunk.
+const mockChunkSize = 64
+
+// MockPromptKey returns the key MockProvider looks responses up by: the hex
+// SHA-256 of the normalized prompt, so case and whitespace do not matter.
+func MockPromptKey(prompt string) string {
+	sum := sha256.Sum256([]byte(cache.NormalizePrompt(prompt)))
+	return hex.EncodeToString(sum[:])
+}
+
+// MockProvider returns canned responses without any network access, for
+// tests, CI and demos. Responses are keyed by MockPromptKey; prompts without
+// one get the fallback page, or a generated page quoting the prompt.
+type MockProvider struct {
+	name      string
+	settings  Settings
+	responses map[string]string
+	fallback  string
+}
+
+// NewMockProvider creates a MockProvider. responses maps MockPromptKey values
+// to raw responses; fallback may be empty.
+func NewMockProvider(name string, s Settings, responses map[string]string, fallback string) *MockProvider {
+	if name == "" {
+		name = "mock"
+	}
+	if s.DefaultModel == "" {
+		s.DefaultModel = "mock-1"
+	}
+	if len(s.Models) == 0 {
+		s.Models = []ModelInfo{{ID: s.DefaultModel, DisplayName: "Mock", Provider: name}}
+	}
+	return &MockProvider{name: name, settings: s, responses: responses, fallback: fallback}
+}
+
+// LoadMockResponses reads canned responses from dir. Each <key>.html file is
+// the response for the prompt with that MockPromptKey; default.html, if
+// present, is returned as the fallback.
+func LoadMockResponses(dir string) (responses map[string]string, fallback string, err error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, "", fmt.Errorf("mock: %w", err)
+	}
+	responses = make(map[string]string, len(files))
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, "", fmt.Errorf("mock: %w", err)
+		}
+		key := strings.TrimSuffix(filepath.Base(f), ".html")
+		if key == "default" {
+			fallback = string(b)
+			continue
+		}
+		responses[key] = string(b)
+	}
+	return responses, fallback, nil
+}
+
+func (p *MockProvider) Name() string        { return p.name }
+func (p *MockProvider) Enabled() bool       { return true }
+func (p *MockProvider) Models() []ModelInfo { return p.settings.Models }
+
+func (p *MockProvider) Generate(ctx context.Context, req models.GenerationRequest) (Response, error) {
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	text, ok := p.responses[MockPromptKey(req.Prompt)]
+	switch {
+	case ok:
+	case p.fallback != "":
+		text = p.fallback
+	default:
+		text = mockPage(req.Prompt)
+	}
+	return Response{
+		Text:             text,
+		FinishReason:     "stop",
+		Model:            p.settings.model(req, p.name),
+		PromptTokens:     mockTokens(buildUserPrompt(req)),
+		CompletionTokens: mockTokens(text),
+	}, nil
+}
+
+// GenerateStream implements StreamingProvider by splitting the canned
+// response into fixed-size chunks.
+func (p *MockProvider) GenerateStream(ctx context.Context, req models.GenerationRequest, onChunk func(string) error) (Response, error) {
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	for rest := resp.Text; rest != ""; {
+		n := min(mockChunkSize, len(rest))
+		for n < len(rest) && !utf8.RuneStart(rest[n]) {
+			n-- // never split a multi-byte character
+		}
+		if err := onChunk(rest[:n]); err != nil {
+			return Response{}, err
+		}
+		rest = rest[n:]
+	}
+	return resp, nil
+}
+
+// mockTokens approximates a token count as one per four bytes.
+func mockTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// mockPage is the deterministic page returned when no canned response matches.
+func mockPage(prompt string) string {
+	p := html.EscapeString(prompt)
+	return `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>Mock page</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; padding: 4rem 1.5rem; background: #f8fafc; color: #0f172a; }
+main { max-width: 40rem; margin: 0 auto; }
+h1 { font-size: 2rem; margin-bottom: 1rem; }
+</style>
+</head>
+<body>
+<main>
+<h1>Mock page</h1>
+<p>` + p + `</p>
+</main>
+</body>
+</html>`
+}
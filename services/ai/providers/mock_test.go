@@ -0,0 +1,53 @@
+package providers_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/providers"
+)
+
+func TestMock_ReturnsCannedResponseByPromptHash(t *testing.T) {
+	page := "<html><body><h1>Bakery</h1></body></html>"
+	p := providers.NewMockProvider("", providers.Settings{}, map[string]string{
+		providers.MockPromptKey("A bakery landing page"): page,
+	}, "")
+
+	resp, err := p.Generate(context.Background(), models.GenerationRequest{Prompt: "  a BAKERY landing page "})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if resp.Text != page || resp.Model != "mock-1" || resp.TotalTokens() == 0 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	other, _ := p.Generate(context.Background(), models.GenerationRequest{Prompt: "a <b>florist</b>"})
+	if !strings.Contains(other.Text, "a &lt;b&gt;florist&lt;/b&gt;") {
+		t.Errorf("expected the generated page to quote the escaped prompt, got %q", other.Text)
+	}
+}
+
+func TestMock_StreamsWholeCharacters(t *testing.T) {
+	page := "<html><body><p>" + strings.Repeat("café ", 40) + "</p></body></html>"
+	p := providers.NewMockProvider("demo", providers.Settings{}, nil, page)
+
+	var chunks []string
+	resp, err := p.GenerateStream(context.Background(), models.GenerationRequest{}, func(s string) error {
+		chunks = append(chunks, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream: %v", err)
+	}
+	if len(chunks) < 2 || strings.Join(chunks, "") != page || resp.Text != page {
+		t.Fatalf("stream did not reassemble the fallback page (%d chunks)", len(chunks))
+	}
+	for _, c := range chunks {
+		if !utf8.ValidString(c) {
+			t.Errorf("chunk split a character: %q", c)
+		}
+	}
+}
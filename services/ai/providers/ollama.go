@@ -0,0 +1,317 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+)
+
+const ollamaSystemPrompt = `You are an expert HTML/CSS developer.
+Given a user's description, generate a complete, self-contained HTML page with embedded CSS.
+Output ONLY the raw HTML — no explanations, no markdown, no code fences.
+The HTML must include a <style> block inside <head> for all CSS.
+Use semantic HTML5 elements, responsive design, and modern CSS.`
+
+const (
+	// ollamaDiscoveryTTL is how long the model list from /api/tags is reused.
+	ollamaDiscoveryTTL = 30 * time.Second
+	// ollamaProbeTimeout bounds a /api/tags call. Probes run in the
+	// background, so an absent server never stalls routing.
+	ollamaProbeTimeout = 2 * time.Second
+)
+
+// ollamaDefaults are used for any Settings field left empty.
+var ollamaDefaults = Settings{
+	BaseURL:      "http://localhost:11434",
+	DefaultModel: "qwen2.5-coder:7b",
+	Models: []ModelInfo{
+		{ID: "qwen2.5-coder:7b", DisplayName: "Qwen2.5 Coder 7B (local)", Provider: "ollama"},
+	},
+}
+
+// OllamaProvider calls a local Ollama server through its native /api/chat
+// endpoint, so pages can be generated without any cloud API key.
+//
+// The provider is enabled only while the server is reachable and has at least
+// one model pulled. Installed models are discovered from /api/tags in the
+// background and cached for ollamaDiscoveryTTL; when the configured default
+// model is not installed the first installed one is used instead.
+type OllamaProvider struct {
+	settings Settings
+	client   *http.Client
+	probe    *http.Client
+
+	mu         sync.Mutex
+	installed  []string
+	checkedAt  time.Time
+	refreshing bool
+}
+
+// NewOllamaProvider creates an OllamaProvider and starts probing the server
+// in the background. Until the first probe answers, the provider reports
+// itself disabled.
+func NewOllamaProvider(s Settings) *OllamaProvider {
+	s = s.withDefaults(ollamaDefaults)
+	p := &OllamaProvider{
+		settings:   s,
+		client:     s.httpClient(),
+		probe:      &http.Client{Timeout: ollamaProbeTimeout, Transport: s.Transport},
+		refreshing: true,
+	}
+	go p.discover()
+	return p
+}
+
+func (p *OllamaProvider) Name() string { return "ollama" }
+
+// Enabled reports whether the server answered the last discovery with at
+// least one installed model.
+func (p *OllamaProvider) Enabled() bool {
+	return len(p.discovered()) > 0
+}
+
+// Models returns the installed models, using configured display names where
+// they match, or the configured list while the server is unreachable.
+func (p *OllamaProvider) Models() []ModelInfo {
+	installed := p.discovered()
+	if len(installed) == 0 {
+		return p.settings.Models
+	}
+	found := make([]ModelInfo, 0, len(installed))
+	for _, id := range installed {
+		found = append(found, ModelInfo{ID: id})
+	}
+	return mergeModels(p.Name(), found, p.settings.Models)
+}
+
+// discovered returns the cached installed model names, refreshing them in
+// the background once they are older than ollamaDiscoveryTTL. It never
+// blocks on the server.
+func (p *OllamaProvider) discovered() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.refreshing && time.Since(p.checkedAt) > ollamaDiscoveryTTL {
+		p.refreshing = true
+		go p.discover()
+	}
+	return p.installed
+}
+
+// discover lists installed models via GET /api/tags.
+func (p *OllamaProvider) discover() {
+	installed, err := p.listTags()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil && (p.checkedAt.IsZero() || len(p.installed) > 0) {
+		log.Printf("[ai-service] ollama: model discovery failed: %v", err)
+	}
+	p.installed = installed
+	p.checkedAt = time.Now()
+	p.refreshing = false
+}
+
+func (p *OllamaProvider) listTags() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ollamaProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.settings.BaseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.probe.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(p.Name(), resp)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("decode tags: %w", err)
+	}
+	out := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		out = append(out, m.Name)
+	}
+	return out, nil
+}
+
+// model returns the model to call for req. A pinned model is used as is; the
+// default falls back to the first installed model when it is not installed.
+func (p *OllamaProvider) model(req models.GenerationRequest) string {
+	model := p.settings.model(req, p.Name())
+	if req.PreferredModel != "" && req.PreferredProvider == p.Name() {
+		return model
+	}
+	installed := p.discovered()
+	for _, id := range installed {
+		if id == model {
+			return model
+		}
+	}
+	if len(installed) > 0 {
+		return installed[0]
+	}
+	return model
+}
+
+func (p *OllamaProvider) Generate(ctx context.Context, req models.GenerationRequest) (Response, error) {
+	resp, err := p.doRequest(ctx, req, false)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	var result ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Response{}, fmt.Errorf("ollama: decode response: %w", err)
+	}
+	if result.Error != "" {
+		return Response{}, fmt.Errorf("ollama: %s", result.Error)
+	}
+	if strings.TrimSpace(result.Message.Content) == "" {
+		return Response{}, fmt.Errorf("ollama: empty response")
+	}
+	out := Response{Text: result.Message.Content}
+	result.merge(&out)
+	return out, nil
+}
+
+// GenerateStream implements StreamingProvider. Ollama streams newline-delimited
+// JSON objects rather than Server-Sent Events; the last one has done=true and
+// carries the usage counts.
+func (p *OllamaProvider) GenerateStream(ctx context.Context, req models.GenerationRequest, onChunk func(string) error) (Response, error) {
+	resp, err := p.doRequest(ctx, req, true)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	return streamOllamaContent(resp.Body, onChunk)
+}
+
+// doRequest sends a chat request and returns the response once a 200 status
+// has been received. The caller must close the body.
+func (p *OllamaProvider) doRequest(ctx context.Context, req models.GenerationRequest, stream bool) (*http.Response, error) {
+	model := p.model(req)
+	temperature, maxTokens := p.settings.params(model)
+	payload := map[string]any{
+		"model":    model,
+		"messages": chatMessages(req, ollamaSystemPrompt),
+		"stream":   stream,
+		"options": map[string]any{
+			"temperature": temperature,
+			"num_predict": maxTokens,
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: marshal: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		p.settings.BaseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("ollama: new request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, transportError("ollama", "do request", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("ollama", resp)
+	}
+
+	return resp, nil
+}
+
+// ollamaChatResponse is the shape of an /api/chat response, and of each line
+// of a streamed one.
+type ollamaChatResponse struct {
+	Model   string `json:"model"`
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+// merge copies the model, finish reason and usage from r into resp. Ollama
+// reports "stop" or "length", which Response.Truncated understands.
+func (r ollamaChatResponse) merge(resp *Response) {
+	if r.Model != "" {
+		resp.Model = r.Model
+	}
+	if r.DoneReason != "" {
+		resp.FinishReason = r.DoneReason
+	}
+	if r.Done {
+		resp.PromptTokens = r.PromptEvalCount
+		resp.CompletionTokens = r.EvalCount
+	}
+}
+
+// streamOllamaContent reads a streamed /api/chat body, forwarding each content
+// fragment to onChunk.
+func streamOllamaContent(r io.Reader, onChunk func(string) error) (Response, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineBytes)
+
+	var resp Response
+	var sb strings.Builder
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return Response{}, fmt.Errorf("ollama: decode stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return Response{}, fmt.Errorf("ollama: %s", chunk.Error)
+		}
+		chunk.merge(&resp)
+		if text := chunk.Message.Content; text != "" {
+			sb.WriteString(text)
+			if err := onChunk(text); err != nil {
+				return Response{}, err
+			}
+		}
+		if chunk.Done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Response{}, fmt.Errorf("ollama: read stream: %w", err)
+	}
+	if sb.Len() == 0 {
+		return Response{}, fmt.Errorf("ollama: empty stream")
+	}
+	resp.Text = sb.String()
+	return resp, nil
+}
@@ -0,0 +1,135 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/providers"
+)
+
+// newOllamaServer stands in for a local Ollama with the given models pulled.
+// It records the model of the last chat request.
+func newOllamaServer(t *testing.T, installed []string, lastModel *string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			var tags []map[string]string
+			for _, m := range installed {
+				tags = append(tags, map[string]string{"name": m})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"models": tags})
+		case "/api/chat":
+			var payload struct {
+				Model  string `json:"model"`
+				Stream bool   `json:"stream"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			*lastModel = payload.Model
+			if !payload.Stream {
+				fmt.Fprintf(w, `{"model":%q,"message":{"content":"<html></html>"},"done":true,"done_reason":"stop","prompt_eval_count":5,"eval_count":4}`, payload.Model)
+				return
+			}
+			for _, c := range []string{"<html>", "</html>"} {
+				fmt.Fprintf(w, "{\"model\":%q,\"message\":{\"content\":%q},\"done\":false}\n", payload.Model, c)
+			}
+			fmt.Fprintf(w, "{\"model\":%q,\"message\":{\"content\":\"\"},\"done\":true,\"done_reason\":\"length\",\"prompt_eval_count\":5,\"eval_count\":4}\n", payload.Model)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// awaitDiscovery waits for the background probe started by the constructor.
+func awaitDiscovery(t *testing.T, p *providers.OllamaProvider) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !p.Enabled() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected provider to be enabled when models are installed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOllama_DiscoversInstalledModels(t *testing.T) {
+	var model string
+	srv := newOllamaServer(t, []string{"llama3.2:3b", "qwen2.5-coder:7b"}, &model)
+	p := providers.NewOllamaProvider(providers.Settings{BaseURL: srv.URL})
+	awaitDiscovery(t, p)
+
+	ms := p.Models()
+	if len(ms) != 2 || ms[0].ID != "llama3.2:3b" || ms[1].DisplayName != "Qwen2.5 Coder 7B (local)" || ms[0].Provider != "ollama" {
+		t.Errorf("unexpected models: %+v", ms)
+	}
+
+	resp, err := p.Generate(context.Background(), models.GenerationRequest{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if model != "qwen2.5-coder:7b" || resp.Text != "<html></html>" || resp.TotalTokens() != 9 {
+		t.Errorf("model=%q resp=%+v", model, resp)
+	}
+}
+
+func TestOllama_FallsBackToInstalledModel(t *testing.T) {
+	var model string
+	srv := newOllamaServer(t, []string{"llama3.2:3b"}, &model)
+	p := providers.NewOllamaProvider(providers.Settings{BaseURL: srv.URL})
+	awaitDiscovery(t, p)
+
+	var chunks []string
+	resp, err := p.GenerateStream(context.Background(), models.GenerationRequest{}, func(s string) error {
+		chunks = append(chunks, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream: %v", err)
+	}
+	if model != "llama3.2:3b" {
+		t.Errorf("expected the installed model to be used, got %q", model)
+	}
+	if strings.Join(chunks, "") != "<html></html>" || !resp.Truncated() || resp.CompletionTokens != 4 {
+		t.Errorf("chunks=%q resp=%+v", chunks, resp)
+	}
+}
+
+func TestOllama_DisabledWhenUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	p := providers.NewOllamaProvider(providers.Settings{BaseURL: url})
+	if p.Enabled() {
+		t.Error("expected provider to be disabled when the server is down")
+	}
+	if len(p.Models()) == 0 {
+		t.Error("expected the configured models while the server is down")
+	}
+}
+
+func TestOllama_RejectsEmptyResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			fmt.Fprint(w, `{"models":[{"name":"qwen2.5-coder:7b"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"model":"qwen2.5-coder:7b","message":{"content":""},"done":true,"done_reason":"stop"}`)
+	}))
+	defer srv.Close()
+	p := providers.NewOllamaProvider(providers.Settings{BaseURL: srv.URL})
+	awaitDiscovery(t, p)
+
+	if _, err := p.Generate(context.Background(), models.GenerationRequest{}); err == nil || err.Error() != "ollama: empty response" {
+		t.Errorf("err = %v, want an empty response error", err)
+	}
+}
@@ -0,0 +1,239 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/zest-app/ai-service/models"
+)
+
+const openAISystemPrompt = `You are an expert HTML/CSS developer.
+Given a user's description, generate a complete, self-contained HTML page with embedded CSS.
+Output ONLY the raw HTML — no explanations, no markdown, no code fences.
+The HTML must include a <style> block inside <head> for all CSS.
+Use semantic HTML5 elements, responsive design, and modern CSS.`
+
+// Auth schemes supported by OpenAICompatibleProvider.
+const (
+	// AuthBearer sends "Authorization: Bearer <key>" (OpenAI, Groq, Together).
+	AuthBearer = "bearer"
+	// AuthHeader sends the raw key in OpenAICompatibleConfig.AuthHeader
+	// (e.g. Azure OpenAI's "api-key").
+	AuthHeader = "header"
+	// AuthNone sends no credentials (vLLM, LM Studio on localhost). The
+	// provider is enabled without an API key.
+	AuthNone = "none"
+)
+
+// OpenAICompatibleConfig configures an OpenAICompatibleProvider.
+type OpenAICompatibleConfig struct {
+	// Name identifies the provider in routing and /models.
+	Name string
+	// Settings.BaseURL is the API root, e.g. https://api.openai.com/v1;
+	// requests go to BaseURL + "/chat/completions".
+	Settings
+	// AuthScheme is one of AuthBearer (default), AuthHeader or AuthNone.
+	AuthScheme string
+	// AuthHeader is the header carrying the key for AuthHeader.
+	AuthHeader string
+	// Headers are added to every request.
+	Headers map[string]string
+	// SystemPrompt replaces the default HTML generation prompt.
+	SystemPrompt string
+}
+
+// OpenAICompatibleProvider calls any endpoint implementing the OpenAI chat
+// completions API.
+type OpenAICompatibleProvider struct {
+	cfg     OpenAICompatibleConfig
+	client  *http.Client
+	catalog *modelCatalog
+	keys    *KeyPool
+	// token returns the credential to send for a pooled key; it defaults to
+	// the key itself. Copilot swaps in its session token exchange.
+	token func(ctx context.Context, key string) (string, error)
+}
+
+// NewOpenAICompatibleProvider creates an OpenAICompatibleProvider.
+func NewOpenAICompatibleProvider(cfg OpenAICompatibleConfig) *OpenAICompatibleProvider {
+	if cfg.AuthScheme == "" {
+		cfg.AuthScheme = AuthBearer
+	}
+	if cfg.SystemPrompt == "" {
+		cfg.SystemPrompt = openAISystemPrompt
+	}
+	if cfg.DefaultModel == "" && len(cfg.Models) > 0 {
+		cfg.DefaultModel = cfg.Models[0].ID
+	}
+	p := &OpenAICompatibleProvider{cfg: cfg, client: cfg.httpClient(), keys: cfg.keyPool()}
+	p.token = func(_ context.Context, key string) (string, error) { return key, nil }
+	p.catalog = newModelCatalog(cfg.Name, cfg.Settings, p.listModels)
+	return p
+}
+
+func (p *OpenAICompatibleProvider) Name() string { return p.cfg.Name }
+
+// Enabled reports whether credentials are configured, or none are needed.
+func (p *OpenAICompatibleProvider) Enabled() bool {
+	return p.cfg.AuthScheme == AuthNone || p.keys.Len() > 0
+}
+
+// KeyStatus reports usage of each pooled API key.
+func (p *OpenAICompatibleProvider) KeyStatus() []KeyStatus { return p.keys.Status() }
+
+// Models returns the models discovered from GET /models when discovery is
+// enabled, otherwise the configured list.
+func (p *OpenAICompatibleProvider) Models() []ModelInfo { return p.catalog.models() }
+
+func (p *OpenAICompatibleProvider) Generate(ctx context.Context, req models.GenerationRequest) (Response, error) {
+	resp, err := p.doRequest(ctx, req, false)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	return extractOpenAIContent(resp.Body)
+}
+
+// GenerateStream implements StreamingProvider using `stream: true`.
+func (p *OpenAICompatibleProvider) GenerateStream(ctx context.Context, req models.GenerationRequest, onChunk func(string) error) (Response, error) {
+	resp, err := p.doRequest(ctx, req, true)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	return streamOpenAIContent(resp.Body, onChunk)
+}
+
+// doRequest sends a chat completion request and returns the response once a
+// 200 status has been received. The caller must close the body.
+func (p *OpenAICompatibleProvider) doRequest(ctx context.Context, req models.GenerationRequest, stream bool) (*http.Response, error) {
+	name := p.Name()
+	model := p.cfg.model(req, name)
+	temperature, maxTokens := p.cfg.params(model)
+	payload := map[string]any{
+		"model":       model,
+		"messages":    chatMessages(req, p.cfg.SystemPrompt),
+		"temperature": temperature,
+		"max_tokens":  maxTokens,
+		"stream":      stream,
+	}
+	if stream {
+		payload["stream_options"] = map[string]any{"include_usage": true}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s: marshal: %w", name, err)
+	}
+
+	return p.send(ctx, http.MethodPost, "/chat/completions", bytes.NewReader(body), "do request")
+}
+
+// send makes a request to BaseURL+path carrying the configured headers and a
+// pooled credential, and returns the response once a 200 status has been
+// received. The caller must close the body.
+func (p *OpenAICompatibleProvider) send(ctx context.Context, method, path string, body io.Reader, op string) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, p.cfg.BaseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: new request: %w", p.Name(), err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range p.cfg.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	keys := p.keys
+	if p.cfg.AuthScheme == AuthNone {
+		keys = nil
+	}
+	return sendWithKey(p.client, keys, p.Name(), op, httpReq, func(r *http.Request, key string) error {
+		token, err := p.token(ctx, key)
+		if err != nil {
+			return err
+		}
+		if p.cfg.AuthScheme == AuthHeader {
+			r.Header.Set(p.cfg.AuthHeader, token)
+		} else {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		return nil
+	})
+}
+
+// openAIModelList is the shape of GET /models. Copilot adds capabilities to
+// each entry; plain OpenAI-compatible servers return only the ID.
+type openAIModelList struct {
+	Data []struct {
+		ID           string `json:"id"`
+		Name         string `json:"name"`
+		Capabilities *struct {
+			Type   string `json:"type"`
+			Limits struct {
+				MaxContextWindowTokens int `json:"max_context_window_tokens"`
+				MaxOutputTokens        int `json:"max_output_tokens"`
+			} `json:"limits"`
+			Supports struct {
+				Vision bool `json:"vision"`
+			} `json:"supports"`
+		} `json:"capabilities"`
+	} `json:"data"`
+}
+
+// nonChatModelMarkers identify models in a plain /models listing that cannot
+// serve chat completions.
+var nonChatModelMarkers = []string{"embed", "whisper", "tts", "dall-e", "moderation", "rerank", "transcribe"}
+
+// listModels fetches the chat models offered at GET /models.
+func (p *OpenAICompatibleProvider) listModels(ctx context.Context) ([]ModelInfo, error) {
+	resp, err := p.send(ctx, http.MethodGet, "/models", nil, "list models")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list openAIModelList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("%s: decode models: %w", p.Name(), err)
+	}
+	var out []ModelInfo
+	for _, m := range list.Data {
+		if c := m.Capabilities; c != nil {
+			if c.Type != "chat" {
+				continue
+			}
+			out = append(out, ModelInfo{
+				ID:              m.ID,
+				DisplayName:     m.Name,
+				ContextWindow:   c.Limits.MaxContextWindowTokens,
+				MaxOutputTokens: c.Limits.MaxOutputTokens,
+				Vision:          c.Supports.Vision,
+			})
+			continue
+		}
+		if !isChatModelID(m.ID) {
+			continue
+		}
+		out = append(out, ModelInfo{ID: m.ID, DisplayName: m.Name})
+	}
+	return out, nil
+}
+
+// isChatModelID reports whether id does not look like an embedding, audio,
+// image or moderation model.
+func isChatModelID(id string) bool {
+	id = strings.ToLower(id)
+	for _, marker := range nonChatModelMarkers {
+		if strings.Contains(id, marker) {
+			return false
+		}
+	}
+	return true
+}
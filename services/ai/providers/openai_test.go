@@ -0,0 +1,174 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/providers"
+)
+
+// chatServer is an httptest stand-in for an OpenAI-compatible endpoint. It
+// records the last request and answers with reply.
+type chatServer struct {
+	*httptest.Server
+	header  http.Header
+	payload map[string]any
+}
+
+func newChatServer(t *testing.T, handle func(w http.ResponseWriter, stream bool)) *chatServer {
+	t.Helper()
+	s := &chatServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		s.header = r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&s.payload)
+		stream, _ := s.payload["stream"].(bool)
+		handle(w, stream)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func chatReply(w http.ResponseWriter, stream bool) {
+	if stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range []string{"<html>", "</html>"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", c)
+		}
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":7,\"completion_tokens\":3}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		return
+	}
+	fmt.Fprint(w, `{"model":"served-model","choices":[{"message":{"content":"<html></html>"},"finish_reason":"stop"}],"usage":{"prompt_tokens":7,"completion_tokens":3}}`)
+}
+
+func TestOpenAICompatible_Generate(t *testing.T) {
+	srv := newChatServer(t, chatReply)
+	temp := 0.2
+	p := providers.NewOpenAICompatibleProvider(providers.OpenAICompatibleConfig{
+		Name: "groq",
+		Settings: providers.Settings{
+			APIKey:      "secret",
+			BaseURL:     srv.URL + "/v1",
+			Models:      []providers.ModelInfo{{ID: "llama-3.3-70b", Provider: "groq"}},
+			ModelParams: map[string]providers.GenParams{"llama-3.3-70b": {Temperature: &temp, MaxTokens: 1024}},
+		},
+		Headers: map[string]string{"X-Org": "zest"},
+	})
+
+	resp, err := p.Generate(context.Background(), models.GenerationRequest{Prompt: "a page"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if resp.Text != "<html></html>" || resp.Model != "served-model" || resp.TotalTokens() != 10 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if got := srv.header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := srv.header.Get("X-Org"); got != "zest" {
+		t.Errorf("X-Org = %q", got)
+	}
+	if srv.payload["model"] != "llama-3.3-70b" || srv.payload["temperature"] != 0.2 || srv.payload["max_tokens"] != 1024.0 {
+		t.Errorf("unexpected payload: %v", srv.payload)
+	}
+}
+
+func TestOpenAICompatible_GenerateStream(t *testing.T) {
+	srv := newChatServer(t, chatReply)
+	p := providers.NewOpenAICompatibleProvider(providers.OpenAICompatibleConfig{
+		Name:       "lmstudio",
+		Settings:   providers.Settings{BaseURL: srv.URL + "/v1", DefaultModel: "qwen2.5-coder"},
+		AuthScheme: providers.AuthNone,
+	})
+	if !p.Enabled() {
+		t.Fatal("expected a provider without auth to be enabled without a key")
+	}
+
+	var chunks []string
+	resp, err := p.GenerateStream(context.Background(), models.GenerationRequest{}, func(s string) error {
+		chunks = append(chunks, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream: %v", err)
+	}
+	if strings.Join(chunks, "") != "<html></html>" || resp.CompletionTokens != 3 {
+		t.Errorf("chunks=%q resp=%+v", chunks, resp)
+	}
+	if _, ok := srv.header["Authorization"]; ok {
+		t.Error("expected no Authorization header with AuthNone")
+	}
+}
+
+func TestOpenAICompatible_HeaderAuthAndErrors(t *testing.T) {
+	srv := newChatServer(t, func(w http.ResponseWriter, _ bool) {
+		w.Header().Set("Retry-After", "2")
+		http.Error(w, `{"error":"slow down"}`, http.StatusTooManyRequests)
+	})
+	p := providers.NewOpenAICompatibleProvider(providers.OpenAICompatibleConfig{
+		Name:       "azure",
+		Settings:   providers.Settings{APIKey: "k", BaseURL: srv.URL + "/v1", DefaultModel: "gpt-4o"},
+		AuthScheme: providers.AuthHeader,
+		AuthHeader: "api-key",
+	})
+
+	_, err := p.Generate(context.Background(), models.GenerationRequest{})
+	var perr *providers.Error
+	if !errors.As(err, &perr) || perr.Kind != providers.ErrRateLimited || perr.Provider != "azure" {
+		t.Fatalf("expected rate-limit error from azure, got %v", err)
+	}
+	if got := srv.header.Get("api-key"); got != "k" {
+		t.Errorf("api-key = %q", got)
+	}
+}
+
+func TestOpenAICompatible_DiscoversChatModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" || r.Header.Get("Authorization") != "Bearer k" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"data":[
+			{"id":"gpt-5","name":"GPT-5","capabilities":{"type":"chat","limits":{"max_context_window_tokens":400000,"max_output_tokens":128000},"supports":{"vision":true}}},
+			{"id":"text-embedding-3-small","capabilities":{"type":"embeddings"}},
+			{"id":"gpt-4o"}
+		]}`)
+	}))
+	defer srv.Close()
+
+	p := providers.NewOpenAICompatibleProvider(providers.OpenAICompatibleConfig{
+		Name: "copilot-like",
+		Settings: providers.Settings{
+			APIKey:    "k",
+			BaseURL:   srv.URL,
+			ModelsTTL: time.Hour,
+			Models:    []providers.ModelInfo{{ID: "gpt-4o", DisplayName: "GPT-4o", Vision: true}},
+		},
+	})
+
+	var got []providers.ModelInfo
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if got = p.Models(); len(got) == 2 && got[0].ID == "gpt-5" {
+			break
+		}
+	}
+	want := []providers.ModelInfo{
+		{ID: "gpt-5", DisplayName: "GPT-5", Provider: "copilot-like", ContextWindow: 400000, MaxOutputTokens: 128000, Vision: true},
+		{ID: "gpt-4o", DisplayName: "GPT-4o", Provider: "copilot-like", Vision: true},
+	}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("models = %+v, want %+v", got, want)
+	}
+}
@@ -13,13 +13,40 @@ type ModelInfo struct {
 	ID          string `json:"id"`           // e.g. "gemini-2.5-flash"
 	DisplayName string `json:"display_name"` // e.g. "Gemini 2.5 Flash"
 	Provider    string `json:"provider"`     // canonical provider name
+
+	// Capabilities, when known from discovery or configuration.
+	ContextWindow   int  `json:"context_window,omitempty"`
+	MaxOutputTokens int  `json:"max_output_tokens,omitempty"`
+	Vision          bool `json:"vision,omitempty"`
 }
 
 // ProviderInfo is returned by the /models endpoint for one provider.
 type ProviderInfo struct {
-	Name    string      `json:"name"`
-	Enabled bool        `json:"enabled"`
-	Models  []ModelInfo `json:"models"`
+	Name    string        `json:"name"`
+	Enabled bool          `json:"enabled"`
+	Models  []ModelInfo   `json:"models"`
+	Circuit BreakerStatus `json:"circuit"`
+}
+
+// Response is a provider's answer to a generation request, including the usage
+// metadata reported by the vendor.
+type Response struct {
+	Text             string
+	PromptTokens     int
+	CompletionTokens int
+	// FinishReason is the vendor's raw stop reason, e.g. "stop", "length",
+	// "STOP" or "MAX_TOKENS".
+	FinishReason string
+	// Model is the model that actually served the request, which may differ
+	// from the one requested when the vendor aliases or upgrades models.
+	Model string
+	// Provider is the canonical name of the provider; set by the Router.
+	Provider string
+}
+
+// TotalTokens returns prompt plus completion tokens.
+func (r Response) TotalTokens() int {
+	return r.PromptTokens + r.CompletionTokens
 }
 
 // Provider is the interface all LLM clients must satisfy.
@@ -30,6 +57,16 @@ type Provider interface {
 	Enabled() bool
 	// Models returns the list of models this provider exposes.
 	Models() []ModelInfo
-	// Generate sends a generation request and returns a raw LLM response string.
-	Generate(ctx context.Context, req models.GenerationRequest) (string, error)
+	// Generate sends a generation request and returns the raw LLM response.
+	Generate(ctx context.Context, req models.GenerationRequest) (Response, error)
+}
+
+// StreamingProvider is implemented by providers that can return partial output
+// while the LLM is still generating.
+type StreamingProvider interface {
+	Provider
+	// GenerateStream sends a generation request and calls onChunk with each
+	// text fragment as it arrives. It returns the full concatenated response.
+	// Returning an error from onChunk aborts the stream.
+	GenerateStream(ctx context.Context, req models.GenerationRequest, onChunk func(string) error) (Response, error)
 }
@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryConfig controls same-provider retries for transient failures
+// (rate limits, 5xx, timeouts) before the router falls back to the next
+// provider. Retries happen within one attempt and do not count against BR-006.
+type RetryConfig struct {
+	// MaxRetries is the number of extra calls after the first one.
+	MaxRetries int
+	// BaseDelay and MaxDelay bound the exponential backoff; the actual delay
+	// is drawn uniformly from [0, min(MaxDelay, BaseDelay*2^n)] ("full jitter").
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// MaxRetryAfter is the longest Retry-After the router will wait out; a
+	// provider asking for longer is treated as unavailable and skipped.
+	MaxRetryAfter time.Duration
+	// Sleep waits for d or until ctx is done. Tests inject a fake one.
+	// Defaults to a timer-based sleep.
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// DefaultRetryConfig retries twice with 0.5s–4s backoff and waits out a
+// Retry-After of up to 10s — comfortably inside the BR-003 60s budget.
+var DefaultRetryConfig = RetryConfig{
+	MaxRetries:    2,
+	BaseDelay:     500 * time.Millisecond,
+	MaxDelay:      4 * time.Second,
+	MaxRetryAfter: 10 * time.Second,
+}
+
+// withDefaults fills zero fields from DefaultRetryConfig. A negative
+// MaxRetries disables retries.
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.MaxRetries == 0 {
+		c.MaxRetries = DefaultRetryConfig.MaxRetries
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = DefaultRetryConfig.BaseDelay
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = DefaultRetryConfig.MaxDelay
+	}
+	if c.MaxRetryAfter <= 0 {
+		c.MaxRetryAfter = DefaultRetryConfig.MaxRetryAfter
+	}
+	if c.Sleep == nil {
+		c.Sleep = sleepContext
+	}
+	return c
+}
+
+// delay returns how long to wait before retry number n (0-based) after err,
+// and false when the error should not be retried on this provider.
+func (c RetryConfig) delay(ctx context.Context, n int, err error) (time.Duration, bool) {
+	var perr *Error
+	if n >= c.MaxRetries || !errors.As(err, &perr) || !perr.Retryable() {
+		return 0, false
+	}
+
+	d := perr.RetryAfter
+	if d > c.MaxRetryAfter {
+		return 0, false
+	}
+	if d == 0 {
+		backoff := c.BaseDelay << n
+		if backoff > c.MaxDelay || backoff <= 0 {
+			backoff = c.MaxDelay
+		}
+		d = time.Duration(rand.Int63n(int64(backoff) + 1))
+	}
+
+	// Leave the remaining budget to the next provider rather than sleeping
+	// into the BR-003 deadline.
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		return 0, false
+	}
+	return d, true
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
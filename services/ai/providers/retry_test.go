@@ -0,0 +1,118 @@
+package providers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/providers"
+)
+
+// flakyProvider fails with errs in order, then succeeds.
+type flakyProvider struct {
+	name  string
+	errs  []error
+	calls int
+}
+
+func (f *flakyProvider) Name() string                  { return f.name }
+func (f *flakyProvider) Enabled() bool                 { return true }
+func (f *flakyProvider) Models() []providers.ModelInfo { return nil }
+
+func (f *flakyProvider) Generate(ctx context.Context, req models.GenerationRequest) (providers.Response, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return providers.Response{}, f.errs[f.calls-1]
+	}
+	return providers.Response{Text: "<html></html>"}, nil
+}
+
+// recordSleeps returns a RetryConfig.Sleep that records delays without waiting.
+func recordSleeps(delays *[]time.Duration) func(context.Context, time.Duration) error {
+	return func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return nil
+	}
+}
+
+func TestRoute_RetriesRateLimitWithRetryAfter(t *testing.T) {
+	p := &flakyProvider{name: "a", errs: []error{
+		&providers.Error{Provider: "a", Kind: providers.ErrRateLimited, StatusCode: 429, RetryAfter: 2 * time.Second},
+	}}
+	next := &fakeProvider{name: "b", enabled: true, out: "<html></html>"}
+	var delays []time.Duration
+	r := providers.NewRouter(p, next).WithRetryConfig(providers.RetryConfig{Sleep: recordSleeps(&delays)})
+
+	resp, err := r.Route(context.Background(), models.GenerationRequest{})
+	if err != nil || resp.Provider != "a" {
+		t.Fatalf("expected success on a after retry, got provider=%q err=%v", resp.Provider, err)
+	}
+	if len(delays) != 1 || delays[0] != 2*time.Second {
+		t.Errorf("expected one 2s Retry-After wait, got %v", delays)
+	}
+	if next.calls != 0 {
+		t.Errorf("expected no fallback, b called %d times", next.calls)
+	}
+}
+
+func TestRoute_BackoffIsBounded(t *testing.T) {
+	serverErr := &providers.Error{Provider: "a", Kind: providers.ErrServer, StatusCode: 503}
+	p := &flakyProvider{name: "a", errs: []error{serverErr, serverErr, serverErr}}
+	next := &fakeProvider{name: "b", enabled: true, out: "<html></html>"}
+	var delays []time.Duration
+	r := providers.NewRouter(p, next).WithRetryConfig(providers.RetryConfig{
+		MaxRetries: 2, BaseDelay: 100 * time.Millisecond, MaxDelay: 150 * time.Millisecond,
+		Sleep: recordSleeps(&delays),
+	})
+
+	resp, err := r.Route(context.Background(), models.GenerationRequest{})
+	if err != nil || resp.Provider != "b" {
+		t.Fatalf("expected fallback to b after retries, got provider=%q err=%v", resp.Provider, err)
+	}
+	if p.calls != 3 {
+		t.Errorf("expected 1 call + 2 retries on a, got %d", p.calls)
+	}
+	for i, d := range delays {
+		if limit := []time.Duration{100 * time.Millisecond, 150 * time.Millisecond}[i]; d < 0 || d > limit {
+			t.Errorf("delay %d = %s, want within [0, %s]", i, d, limit)
+		}
+	}
+}
+
+func TestRoute_AuthFailureSkipsRetry(t *testing.T) {
+	p := &flakyProvider{name: "a", errs: []error{
+		&providers.Error{Provider: "a", Kind: providers.ErrAuth, StatusCode: 401},
+	}}
+	next := &fakeProvider{name: "b", enabled: true, out: "<html></html>"}
+	var delays []time.Duration
+	r := providers.NewRouter(p, next).WithRetryConfig(providers.RetryConfig{Sleep: recordSleeps(&delays)})
+
+	resp, err := r.Route(context.Background(), models.GenerationRequest{})
+	if err != nil || resp.Provider != "b" {
+		t.Fatalf("expected fallback to b, got provider=%q err=%v", resp.Provider, err)
+	}
+	if p.calls != 1 || len(delays) != 0 {
+		t.Errorf("expected no retries on auth failure, got %d calls and delays %v", p.calls, delays)
+	}
+}
+
+func TestRoute_RetryAfterBeyondDeadlineFallsBack(t *testing.T) {
+	p := &flakyProvider{name: "a", errs: []error{
+		&providers.Error{Provider: "a", Kind: providers.ErrRateLimited, StatusCode: 429, RetryAfter: 5 * time.Second},
+	}}
+	next := &fakeProvider{name: "b", enabled: true, out: "<html></html>"}
+	var delays []time.Duration
+	r := providers.NewRouter(p, next).WithRetryConfig(providers.RetryConfig{Sleep: recordSleeps(&delays)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	resp, err := r.Route(ctx, models.GenerationRequest{})
+	if err != nil || resp.Provider != "b" {
+		t.Fatalf("expected fallback to b, got provider=%q err=%v", resp.Provider, err)
+	}
+	if len(delays) != 0 {
+		t.Errorf("expected no wait past the deadline, got %v", delays)
+	}
+}
@@ -2,33 +2,242 @@ package providers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/zest-app/ai-service/models"
 )
 
 const maxFallbackAttempts = 3 // BR-006
 
+// OutputValidator inspects a raw provider response. A non-nil error marks the
+// attempt as failed so the router falls back to the next provider.
+type OutputValidator func(raw string) error
+
+// ErrInvalidOutput wraps OutputValidator failures.
+var ErrInvalidOutput = errors.New("invalid output")
+
 // Router selects and executes providers in order with fallback (BR-005).
 type Router struct {
-	providers []Provider
+	validators []OutputValidator
+	retry      RetryConfig
+	stats      *Stats
+
+	// mu guards the provider set, which Reload swaps at runtime. Requests
+	// take a snapshot when they start and finish on it.
+	mu         sync.RWMutex
+	providers  []Provider
+	breakers   map[string]*Breaker
+	breakerCfg BreakerConfig
+	strategy   string
+	hedgeDelay time.Duration
 }
 
 // NewRouter creates a Router with the ordered provider list.
-// Providers are tried in order; disabled (no API key) providers are skipped.
+// Providers are tried in order; disabled (no API key) providers are skipped,
+// as are providers whose circuit breaker is open.
 func NewRouter(providers ...Provider) *Router {
-	return &Router{providers: providers}
+	r := &Router{
+		providers: providers,
+		retry:     DefaultRetryConfig.withDefaults(),
+		stats:     NewStats(),
+		strategy:  StrategyStatic,
+	}
+	return r.WithBreakerConfig(DefaultBreakerConfig)
+}
+
+// WithHedgeDelay enables hedged requests for Route: when the current provider
+// has not answered after d, the next candidate is started concurrently and
+// the first valid result wins. Every hedge counts as an attempt (BR-006).
+// Zero disables hedging. RouteStream never hedges, since only one provider
+// can stream to the caller.
+func (r *Router) WithHedgeDelay(d time.Duration) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hedgeDelay = d
+	return r
+}
+
+// WithStrategy selects how providers are ordered: StrategyStatic (the
+// default) or StrategyAdaptive. A preferred provider is always tried first.
+// It may be called at any time, e.g. on configuration reload.
+func (r *Router) WithStrategy(strategy string) *Router {
+	if strategy != StrategyAdaptive {
+		strategy = StrategyStatic
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.strategy = strategy
+	return r
+}
+
+// RoutingStats is the payload of GET /providers/stats.
+type RoutingStats struct {
+	Strategy  string          `json:"strategy"`
+	Providers []ProviderStats `json:"providers"`
+	Models    []ProviderStats `json:"models"`
+	// Keys lists API key usage per provider; counters start over when a
+	// config reload rebuilds the provider.
+	Keys map[string][]KeyStatus `json:"keys,omitempty"`
+}
+
+// Stats returns the observed per-provider and per-model performance that
+// adaptive routing is based on, and the usage of each pooled API key.
+func (r *Router) Stats() RoutingStats {
+	r.mu.RLock()
+	strategy := r.strategy
+	r.mu.RUnlock()
+	providers, _ := r.snapshot()
+	ps, ms := r.stats.Snapshot()
+	out := RoutingStats{Strategy: strategy, Providers: ps, Models: ms}
+	for _, p := range providers {
+		kp, ok := p.(KeyedProvider)
+		if !ok {
+			continue
+		}
+		if keys := kp.KeyStatus(); len(keys) > 0 {
+			if out.Keys == nil {
+				out.Keys = make(map[string][]KeyStatus)
+			}
+			out.Keys[p.Name()] = keys
+		}
+	}
+	return out
+}
+
+// Reload replaces the ordered provider list. Requests already in flight keep
+// using the providers they started with. Breakers are kept for providers whose
+// name is unchanged so a reload does not reset an open circuit.
+func (r *Router) Reload(providers ...Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	breakers := make(map[string]*Breaker, len(providers))
+	for _, p := range providers {
+		if b, ok := r.breakers[p.Name()]; ok {
+			breakers[p.Name()] = b
+		} else {
+			breakers[p.Name()] = NewBreaker(r.breakerCfg)
+		}
+	}
+	r.providers, r.breakers = providers, breakers
+}
+
+// snapshot returns the current provider list and breakers. Both are replaced,
+// never mutated, by Reload.
+func (r *Router) snapshot() ([]Provider, map[string]*Breaker) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.providers, r.breakers
+}
+
+// WithRetryConfig sets the same-provider retry policy. Zero fields take their
+// values from DefaultRetryConfig.
+func (r *Router) WithRetryConfig(cfg RetryConfig) *Router {
+	r.retry = cfg.withDefaults()
+	return r
+}
+
+// WithBreakerConfig replaces every provider's circuit breaker with a fresh one
+// using cfg.
+func (r *Router) WithBreakerConfig(cfg BreakerConfig) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.breakerCfg = cfg
+	r.breakers = make(map[string]*Breaker, len(r.providers))
+	for _, p := range r.providers {
+		r.breakers[p.Name()] = NewBreaker(cfg)
+	}
+	return r
+}
+
+// WithValidators adds output validators run on every successful response.
+// Responses that fail validation count as a failed attempt (BR-006).
+func (r *Router) WithValidators(v ...OutputValidator) *Router {
+	r.validators = append(r.validators, v...)
+	return r
+}
+
+// validate runs all output validators against raw.
+func (r *Router) validate(raw string) error {
+	for _, v := range r.validators {
+		if err := v(raw); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidOutput, err)
+		}
+	}
+	return nil
+}
+
+// PreferenceError reports a PreferredProvider, PreferredModel or
+// StrictProvider value the router cannot honour.
+type PreferenceError struct {
+	Code    string // "invalid_provider" or "invalid_model"
+	Message string
+	// Choices maps each enabled provider to its model IDs.
+	Choices map[string][]string
+}
+
+func (e *PreferenceError) Error() string { return e.Message }
+
+// CheckPreference validates req's provider and model preferences against the
+// enabled providers and their Models(), so a typo is reported to the caller
+// instead of surfacing as a vendor error after fallback.
+func (r *Router) CheckPreference(req models.GenerationRequest) error {
+	if req.PreferredProvider == "" && req.PreferredModel == "" && !req.StrictProvider {
+		return nil
+	}
+	providers, _ := r.snapshot()
+	choices := make(map[string][]string)
+	var pinned Provider
+	for _, p := range providers {
+		if !p.Enabled() {
+			continue
+		}
+		ids := []string{}
+		for _, m := range p.Models() {
+			ids = append(ids, m.ID)
+		}
+		choices[p.Name()] = ids
+		if p.Name() == req.PreferredProvider {
+			pinned = p
+		}
+	}
+	fail := func(code, format string, args ...any) error {
+		return &PreferenceError{Code: code, Message: fmt.Sprintf(format, args...), Choices: choices}
+	}
+
+	switch {
+	case req.PreferredProvider == "":
+		if req.PreferredModel != "" {
+			return fail("invalid_provider", "preferred_model %q requires preferred_provider", req.PreferredModel)
+		}
+		return fail("invalid_provider", "strict_provider requires preferred_provider")
+	case pinned == nil:
+		return fail("invalid_provider", "unknown or disabled provider %q", req.PreferredProvider)
+	case req.PreferredModel != "":
+		for _, id := range choices[pinned.Name()] {
+			if id == req.PreferredModel {
+				return nil
+			}
+		}
+		return fail("invalid_model", "provider %q has no model %q", req.PreferredProvider, req.PreferredModel)
+	}
+	return nil
 }
 
 // AvailableProviders returns ProviderInfo for all registered providers.
 func (r *Router) AvailableProviders() []ProviderInfo {
-	out := make([]ProviderInfo, 0, len(r.providers))
-	for _, p := range r.providers {
+	providers, breakers := r.snapshot()
+	out := make([]ProviderInfo, 0, len(providers))
+	for _, p := range providers {
 		out = append(out, ProviderInfo{
 			Name:    p.Name(),
 			Enabled: p.Enabled(),
 			Models:  p.Models(),
+			Circuit: breakers[p.Name()].Status(),
 		})
 	}
 	return out
@@ -36,46 +245,263 @@ func (r *Router) AvailableProviders() []ProviderInfo {
 
 // Route tries each enabled provider in order, up to maxFallbackAttempts.
 // If req.PreferredProvider is set, that provider is tried first (if enabled),
-// then falls back to the normal order for remaining attempts.
-// Returns the raw LLM response string and the name of the provider used.
-func (r *Router) Route(ctx context.Context, req models.GenerationRequest) (string, string, error) {
-	var errs []string
-	attempts := 0
+// then falls back to the normal order for remaining attempts. A response that
+// fails an OutputValidator is treated the same as a provider error.
+// Returns the raw LLM response with Provider set to the provider used.
+func (r *Router) Route(ctx context.Context, req models.GenerationRequest) (Response, error) {
+	return r.route(ctx, req, nil)
+}
 
-	// Build an ordered list: preferred provider first, then the rest.
-	ordered := r.orderedProviders(req.PreferredProvider)
+// RouteStream is the streaming counterpart of Route. Providers implementing
+// StreamingProvider forward fragments to onChunk as they arrive; others are
+// called with Generate and their whole response is forwarded as one chunk.
+// onChunk receives the name of the provider producing the text so callers can
+// discard partial output when a provider fails mid-stream and the router
+// falls back to the next one.
+func (r *Router) RouteStream(ctx context.Context, req models.GenerationRequest, onChunk func(provider, text string) error) (Response, error) {
+	if onChunk == nil {
+		onChunk = func(string, string) error { return nil }
+	}
+	return r.route(ctx, req, onChunk)
+}
 
-	for _, p := range ordered {
-		if !p.Enabled() {
-			continue
+// route implements Route and RouteStream; onChunk is nil when not streaming.
+func (r *Router) route(ctx context.Context, req models.GenerationRequest, onChunk func(provider, text string) error) (Response, error) {
+	// Build an ordered list: preferred provider first, then the rest.
+	providers, breakers := r.snapshot()
+	r.mu.RLock()
+	adaptive := r.strategy == StrategyAdaptive
+	hedgeDelay := r.hedgeDelay
+	r.mu.RUnlock()
+	if adaptive {
+		providers = r.stats.rank(providers)
+	}
+	ordered := orderedProviders(providers, req.PreferredProvider)
+	if req.StrictProvider && req.PreferredProvider != "" {
+		// The caller pinned this provider on purpose: no fallback.
+		ordered = ordered[:min(1, len(ordered))]
+		if len(ordered) == 1 && ordered[0].Name() != req.PreferredProvider {
+			ordered = nil
 		}
-		if attempts >= maxFallbackAttempts {
+	}
+	c := &candidates{ordered: ordered, breakers: breakers}
+
+	if onChunk == nil && hedgeDelay > 0 {
+		return r.routeHedged(ctx, req, c, hedgeDelay)
+	}
+
+	var errs []string
+	for {
+		p, breaker, ok := c.next()
+		if !ok {
 			break
 		}
-		attempts++
+		name := p.Name()
+		var forward func(string) error
+		if onChunk != nil {
+			forward = func(text string) error { return onChunk(name, text) }
+		}
 
-		raw, err := p.Generate(ctx, req)
+		resp, err := r.try(ctx, p, breaker, req, forward)
+		if errors.Is(err, ErrContentFiltered) {
+			// The vendor judged the request unsafe; trying another one would
+			// only sidestep its policy.
+			return resp, err
+		}
 		if err != nil {
-			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			errs = append(errs, err.Error())
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		return resp, nil
+	}
+	return Response{}, c.failure(errs)
+}
+
+// candidates yields the providers a request may try, in order, skipping
+// disabled providers and those whose breaker is open, and stopping at the
+// BR-006 attempt limit.
+type candidates struct {
+	ordered  []Provider
+	breakers map[string]*Breaker
+	attempts int
+	skipped  []string
+}
+
+// next returns the next provider to attempt and counts the attempt.
+func (c *candidates) next() (Provider, *Breaker, bool) {
+	for len(c.ordered) > 0 && c.attempts < maxFallbackAttempts {
+		p := c.ordered[0]
+		c.ordered = c.ordered[1:]
+		if !p.Enabled() {
 			continue
 		}
-		return raw, p.Name(), nil
+		breaker := c.breakers[p.Name()]
+		if !breaker.Allow() {
+			c.skipped = append(c.skipped, p.Name())
+			continue
+		}
+		c.attempts++
+		return p, breaker, true
 	}
+	return nil, nil, false
+}
 
+// failure builds the error returned when no attempt succeeded.
+func (c *candidates) failure(errs []string) error {
 	if len(errs) > 0 {
-		return "", "", fmt.Errorf("all providers failed (attempts=%d): %s", attempts, strings.Join(errs, "; "))
+		return fmt.Errorf("all providers failed (attempts=%d): %s", c.attempts, strings.Join(errs, "; "))
+	}
+	if len(c.skipped) > 0 {
+		return fmt.Errorf("all enabled providers unavailable (circuit open): %s", strings.Join(c.skipped, ", "))
 	}
-	return "", "", fmt.Errorf("no providers enabled — configure at least one API key")
+	return fmt.Errorf("no providers enabled — configure at least one API key or start a local Ollama server")
+}
+
+// try runs one attempt on p and records its outcome in the provider's
+// breaker and the routing stats. The response carries the provider name and
+// errors are prefixed with it.
+func (r *Router) try(ctx context.Context, p Provider, breaker *Breaker, req models.GenerationRequest, onChunk func(string) error) (Response, error) {
+	name := p.Name()
+	start := time.Now()
+	resp, err := r.attempt(ctx, p, req, onChunk)
+	recordOutcome(ctx, breaker, err)
+	r.recordStats(ctx, name, req, resp, time.Since(start), err)
+	switch {
+	case errors.Is(err, ErrContentFiltered):
+		resp.Provider = name
+		return resp, withProvider(name, err)
+	case err != nil:
+		return Response{}, withProvider(name, err)
+	}
+	resp.Provider = name
+	return resp, nil
+}
+
+// withProvider prefixes err with the provider name unless it already starts
+// with it, as every *Error and most provider errors do.
+func withProvider(name string, err error) error {
+	if strings.HasPrefix(err.Error(), name+": ") {
+		return err
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// recordOutcome feeds the result of an attempt into the provider's breaker.
+// Only failures that say something about the provider's health count: a
+// safety block or output that failed validation came from a working provider,
+// while a rejected request or a caller that went away tells us nothing at all.
+func recordOutcome(ctx context.Context, b *Breaker, err error) {
+	var perr *Error
+	switch {
+	case err == nil, errors.Is(err, ErrContentFiltered), errors.Is(err, ErrInvalidOutput):
+		b.Success()
+	case errors.Is(ctx.Err(), context.Canceled), errors.As(err, &perr) && perr.Kind == ErrBadRequest:
+		b.Abandon()
+	default:
+		b.Failure()
+	}
+}
+
+// recordStats feeds an attempt into the routing stats. Unlike the breaker,
+// output that failed validation counts against the provider, as it cost the
+// caller an attempt; safety blocks and requests abandoned by the caller or
+// rejected as malformed say nothing about the provider and are not recorded.
+func (r *Router) recordStats(ctx context.Context, name string, req models.GenerationRequest, resp Response, d time.Duration, err error) {
+	var perr *Error
+	if errors.Is(err, ErrContentFiltered) || errors.Is(ctx.Err(), context.Canceled) ||
+		errors.As(err, &perr) && perr.Kind == ErrBadRequest {
+		return
+	}
+	model := resp.Model
+	if model == "" && req.PreferredProvider == name {
+		model = req.PreferredModel
+	}
+	if model == "" {
+		model = "default"
+	}
+	r.stats.Record(name, model, d, err == nil)
+}
+
+// attempt runs one provider attempt: the initial call plus up to
+// maxContinuations follow-up calls while the output is truncated, then the
+// output validators.
+func (r *Router) attempt(ctx context.Context, p Provider, req models.GenerationRequest, onChunk func(string) error) (Response, error) {
+	resp, err := r.callWithRetry(ctx, p, req, onChunk)
+	for i := 0; err == nil && resp.Truncated() && i < maxContinuations; i++ {
+		cont := req
+		cont.Context.PartialOutput = resp.Text
+		var next Response
+		if next, err = r.callWithRetry(ctx, p, cont, onChunk); err != nil {
+			err = fmt.Errorf("continuation %d: %w", i+1, err)
+			break
+		}
+		resp = resp.appendContinuation(next)
+	}
+	if err != nil {
+		return resp, err
+	}
+	if resp.Blocked() {
+		return resp, fmt.Errorf("%w (finish reason %s)", ErrContentFiltered, resp.FinishReason)
+	}
+	return resp, r.validate(resp.Text)
+}
+
+// callWithRetry makes a provider call, retrying transient failures with
+// jittered backoff or the provider's Retry-After. Auth and bad-request errors
+// are returned immediately so the router moves to the next provider. A stream
+// that already forwarded text is never retried.
+func (r *Router) callWithRetry(ctx context.Context, p Provider, req models.GenerationRequest, onChunk func(string) error) (Response, error) {
+	streamed := false
+	forward := onChunk
+	if onChunk != nil {
+		forward = func(text string) error {
+			streamed = true
+			return onChunk(text)
+		}
+	}
+
+	for n := 0; ; n++ {
+		resp, err := call(ctx, p, req, forward)
+		if err == nil || streamed {
+			return resp, err
+		}
+		d, ok := r.retry.delay(ctx, n, err)
+		if !ok {
+			return resp, err
+		}
+		log.Printf("[ai-service] %s: retry %d in %s after: %v", p.Name(), n+1, d.Round(time.Millisecond), err)
+		if serr := r.retry.Sleep(ctx, d); serr != nil {
+			return resp, err
+		}
+	}
+}
+
+// call makes a single provider call, streaming when onChunk is set.
+func call(ctx context.Context, p Provider, req models.GenerationRequest, onChunk func(string) error) (Response, error) {
+	if onChunk == nil {
+		return p.Generate(ctx, req)
+	}
+	if sp, ok := p.(StreamingProvider); ok {
+		return sp.GenerateStream(ctx, req, onChunk)
+	}
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	return resp, onChunk(resp.Text)
 }
 
 // orderedProviders returns the provider list with the preferred one moved first.
-func (r *Router) orderedProviders(preferredName string) []Provider {
+func orderedProviders(providers []Provider, preferredName string) []Provider {
 	if preferredName == "" {
-		return r.providers
+		return providers
 	}
-	result := make([]Provider, 0, len(r.providers))
+	result := make([]Provider, 0, len(providers))
 	var rest []Provider
-	for _, p := range r.providers {
+	for _, p := range providers {
 		if p.Name() == preferredName {
 			result = append(result, p)
 		} else {
@@ -0,0 +1,319 @@
+package providers_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/providers"
+)
+
+// fakeProvider is a Provider whose Generate result is fixed.
+type fakeProvider struct {
+	name    string
+	enabled bool
+	out     string
+	err     error
+	calls   int
+}
+
+func (f *fakeProvider) Name() string                  { return f.name }
+func (f *fakeProvider) Enabled() bool                 { return f.enabled }
+func (f *fakeProvider) Models() []providers.ModelInfo { return nil }
+
+func (f *fakeProvider) Generate(ctx context.Context, req models.GenerationRequest) (providers.Response, error) {
+	f.calls++
+	return providers.Response{Text: f.out}, f.err
+}
+
+// fakeStreamer streams its chunks and then fails with err, if set.
+type fakeStreamer struct {
+	fakeProvider
+	chunks []string
+}
+
+func (f *fakeStreamer) GenerateStream(ctx context.Context, req models.GenerationRequest, onChunk func(string) error) (providers.Response, error) {
+	f.calls++
+	for _, c := range f.chunks {
+		if err := onChunk(c); err != nil {
+			return providers.Response{}, err
+		}
+	}
+	if f.err != nil {
+		return providers.Response{}, f.err
+	}
+	return providers.Response{Text: strings.Join(f.chunks, "")}, nil
+}
+
+type chunk struct{ provider, text string }
+
+func TestRouteStream_StreamsChunks(t *testing.T) {
+	p := &fakeStreamer{fakeProvider: fakeProvider{name: "a", enabled: true}, chunks: []string{"<html>", "</html>"}}
+	r := providers.NewRouter(p)
+
+	var got []chunk
+	resp, err := r.RouteStream(context.Background(), models.GenerationRequest{}, func(provider, text string) error {
+		got = append(got, chunk{provider, text})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Text != "<html></html>" || resp.Provider != "a" {
+		t.Errorf("got text=%q provider=%q", resp.Text, resp.Provider)
+	}
+	if len(got) != 2 || got[0] != (chunk{"a", "<html>"}) {
+		t.Errorf("unexpected chunks: %v", got)
+	}
+}
+
+func TestRouteStream_FallsBackAfterPartialOutput(t *testing.T) {
+	failing := &fakeStreamer{
+		fakeProvider: fakeProvider{name: "a", enabled: true, err: errors.New("boom")},
+		chunks:       []string{"<ht"},
+	}
+	unary := &fakeProvider{name: "b", enabled: true, out: "<html></html>"}
+	r := providers.NewRouter(failing, unary)
+
+	var got []chunk
+	resp, err := r.RouteStream(context.Background(), models.GenerationRequest{}, func(provider, text string) error {
+		got = append(got, chunk{provider, text})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Provider != "b" || resp.Text != "<html></html>" {
+		t.Errorf("got text=%q provider=%q", resp.Text, resp.Provider)
+	}
+	want := []chunk{{"a", "<ht"}, {"b", "<html></html>"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("chunks = %v, want %v", got, want)
+	}
+}
+
+func TestRouteStream_SkipsDisabled(t *testing.T) {
+	off := &fakeProvider{name: "off", out: "x"}
+	r := providers.NewRouter(off)
+
+	_, err := r.RouteStream(context.Background(), models.GenerationRequest{}, func(string, string) error { return nil })
+	if err == nil {
+		t.Fatal("expected error when no provider is enabled")
+	}
+	if off.calls != 0 {
+		t.Errorf("disabled provider was called %d times", off.calls)
+	}
+}
+
+func TestRoute_FallsBackOnInvalidOutput(t *testing.T) {
+	prose := &fakeProvider{name: "a", enabled: true, out: "I'm sorry, I can't help with that."}
+	page := &fakeProvider{name: "b", enabled: true, out: "<html><body><h1>Hi</h1></body></html>"}
+	r := providers.NewRouter(prose, page).WithValidators(func(raw string) error {
+		if !strings.Contains(raw, "<") {
+			return errors.New("no html")
+		}
+		return nil
+	})
+
+	resp, err := r.Route(context.Background(), models.GenerationRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Provider != "b" || resp.Text != page.out {
+		t.Errorf("got text=%q provider=%q, want fallback to b", resp.Text, resp.Provider)
+	}
+}
+
+func TestRoute_InvalidOutputCountsAgainstAttempts(t *testing.T) {
+	var ps []providers.Provider
+	for _, name := range []string{"a", "b", "c", "d"} {
+		ps = append(ps, &fakeProvider{name: name, enabled: true, out: "nope"})
+	}
+	r := providers.NewRouter(ps...).WithValidators(func(string) error { return errors.New("bad") })
+
+	_, err := r.Route(context.Background(), models.GenerationRequest{})
+	if err == nil {
+		t.Fatal("expected error when every output is invalid")
+	}
+	if calls := ps[3].(*fakeProvider).calls; calls != 0 {
+		t.Errorf("expected BR-006 to stop after 3 attempts, 4th provider called %d times", calls)
+	}
+}
+
+// scriptedProvider returns its responses in order and records each request.
+type scriptedProvider struct {
+	name      string
+	responses []providers.Response
+	requests  []models.GenerationRequest
+}
+
+func (s *scriptedProvider) Name() string                  { return s.name }
+func (s *scriptedProvider) Enabled() bool                 { return true }
+func (s *scriptedProvider) Models() []providers.ModelInfo { return nil }
+
+func (s *scriptedProvider) Generate(ctx context.Context, req models.GenerationRequest) (providers.Response, error) {
+	s.requests = append(s.requests, req)
+	if len(s.requests) > len(s.responses) {
+		return providers.Response{}, errors.New("unexpected call")
+	}
+	return s.responses[len(s.requests)-1], nil
+}
+
+func TestRoute_ContinuesTruncatedOutput(t *testing.T) {
+	p := &scriptedProvider{name: "a", responses: []providers.Response{
+		{Text: "<html><body><h1>Coffee shop</h1><p>Fresh", FinishReason: "length", PromptTokens: 10, CompletionTokens: 100},
+		{Text: "```html\n<p>Fresh beans daily</p></body></html>\n```", FinishReason: "stop", PromptTokens: 120, CompletionTokens: 20},
+	}}
+	r := providers.NewRouter(p)
+
+	resp, err := r.Route(context.Background(), models.GenerationRequest{Prompt: "coffee"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "<html><body><h1>Coffee shop</h1><p>Fresh beans daily</p></body></html>"; resp.Text != want {
+		t.Errorf("stitched text = %q, want %q", resp.Text, want)
+	}
+	if resp.PromptTokens != 130 || resp.CompletionTokens != 120 || resp.FinishReason != "stop" {
+		t.Errorf("unexpected usage/finish: %+v", resp)
+	}
+	if len(p.requests) != 2 || p.requests[1].Context.PartialOutput != p.responses[0].Text {
+		t.Errorf("expected continuation request carrying the partial output")
+	}
+}
+
+func TestRoute_CapsContinuations(t *testing.T) {
+	cut := providers.Response{Text: "<html><body><p>more", FinishReason: "MAX_TOKENS"}
+	p := &scriptedProvider{name: "a", responses: []providers.Response{cut, cut, cut, cut}}
+	r := providers.NewRouter(p)
+
+	resp, err := r.Route(context.Background(), models.GenerationRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.requests) != 3 {
+		t.Errorf("expected 1 call + 2 continuations, got %d calls", len(p.requests))
+	}
+	if !resp.Truncated() {
+		t.Error("expected response to still be marked truncated")
+	}
+}
+
+func TestRoute_ContentFilterDoesNotFallBack(t *testing.T) {
+	blocked := &scriptedProvider{name: "a", responses: []providers.Response{{FinishReason: "SAFETY"}}}
+	next := &fakeProvider{name: "b", enabled: true, out: "<html></html>"}
+	r := providers.NewRouter(blocked, next)
+
+	resp, err := r.Route(context.Background(), models.GenerationRequest{})
+	if !errors.Is(err, providers.ErrContentFiltered) {
+		t.Fatalf("expected ErrContentFiltered, got %v", err)
+	}
+	if resp.Provider != "a" {
+		t.Errorf("expected provider a to be reported, got %q", resp.Provider)
+	}
+	if next.calls != 0 {
+		t.Errorf("expected no fallback after a safety block, b called %d times", next.calls)
+	}
+}
+
+func TestReload_SwapsProvidersAndKeepsBreakers(t *testing.T) {
+	down := &fakeProvider{name: "a", enabled: true, err: errors.New("boom")}
+	r := providers.NewRouter(down).WithBreakerConfig(providers.BreakerConfig{FailureThreshold: 1})
+	r.Route(context.Background(), models.GenerationRequest{})
+
+	up := &fakeProvider{name: "b", enabled: true, out: "<html></html>"}
+	r.Reload(&fakeProvider{name: "a", enabled: true, out: "<html></html>"}, up)
+
+	resp, err := r.Route(context.Background(), models.GenerationRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Provider != "b" {
+		t.Errorf("expected a's open circuit to survive the reload, got provider %q", resp.Provider)
+	}
+	if got := r.AvailableProviders(); len(got) != 2 || got[1].Name != "b" {
+		t.Errorf("AvailableProviders = %+v", got)
+	}
+}
+
+// catalogProvider is a fakeProvider with a model list.
+type catalogProvider struct {
+	fakeProvider
+	models []string
+}
+
+func (c *catalogProvider) Models() []providers.ModelInfo {
+	var out []providers.ModelInfo
+	for _, id := range c.models {
+		out = append(out, providers.ModelInfo{ID: id, Provider: c.name})
+	}
+	return out
+}
+
+func TestCheckPreference(t *testing.T) {
+	r := providers.NewRouter(
+		&catalogProvider{fakeProvider{name: "gemini", enabled: true}, []string{"gemini-2.5-flash", "gemini-2.5-pro"}},
+		&catalogProvider{fakeProvider{name: "glm", enabled: true}, []string{"glm-4.5"}},
+		&catalogProvider{fakeProvider{name: "copilot"}, []string{"gpt-4o"}},
+	)
+	cases := []struct {
+		req  models.GenerationRequest
+		code string
+	}{
+		{models.GenerationRequest{}, ""},
+		{models.GenerationRequest{PreferredProvider: "gemini", PreferredModel: "gemini-2.5-pro"}, ""},
+		{models.GenerationRequest{PreferredProvider: "glm", StrictProvider: true}, ""},
+		{models.GenerationRequest{PreferredProvider: "gemini", PreferredModel: "gemini-2.5-prp"}, "invalid_model"},
+		{models.GenerationRequest{PreferredProvider: "copilot"}, "invalid_provider"},
+		{models.GenerationRequest{PreferredModel: "glm-4.5"}, "invalid_provider"},
+		{models.GenerationRequest{StrictProvider: true}, "invalid_provider"},
+	}
+	for _, c := range cases {
+		err := r.CheckPreference(c.req)
+		var perr *providers.PreferenceError
+		if c.code == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error %v", c.req, err)
+			}
+			continue
+		}
+		if !errors.As(err, &perr) || perr.Code != c.code {
+			t.Errorf("%+v: got %v, want code %s", c.req, err, c.code)
+			continue
+		}
+		if len(perr.Choices) != 2 || len(perr.Choices["gemini"]) != 2 {
+			t.Errorf("choices = %v, want the enabled providers' models", perr.Choices)
+		}
+	}
+}
+
+func TestRoute_StrictProviderDoesNotFallBack(t *testing.T) {
+	pinned := &fakeProvider{name: "a", enabled: true, err: errors.New("boom")}
+	other := &fakeProvider{name: "b", enabled: true, out: "<html></html>"}
+	r := providers.NewRouter(other, pinned)
+
+	_, err := r.Route(context.Background(), models.GenerationRequest{PreferredProvider: "a", StrictProvider: true})
+	if err == nil {
+		t.Fatal("expected the pinned provider's failure to be returned")
+	}
+	if other.calls != 0 {
+		t.Errorf("strict_provider must not fall back, b called %d times", other.calls)
+	}
+}
+
+func TestRoute_NamesProviderOnceInErrors(t *testing.T) {
+	typed := &fakeProvider{name: "a", enabled: true, err: &providers.Error{Provider: "a", Kind: providers.ErrBadRequest, StatusCode: 400, Message: "bad prompt"}}
+	plain := &fakeProvider{name: "b", enabled: true, err: errors.New("boom")}
+
+	for p, want := range map[*fakeProvider]string{
+		typed: "all providers failed (attempts=1): a: status 400: bad prompt",
+		plain: "all providers failed (attempts=1): b: boom",
+	} {
+		r := providers.NewRouter(p)
+		_, err := r.Route(context.Background(), models.GenerationRequest{PreferredProvider: p.name, StrictProvider: true})
+		if err == nil || err.Error() != want {
+			t.Errorf("error = %v, want %q", err, want)
+		}
+	}
+}
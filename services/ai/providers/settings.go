@@ -0,0 +1,101 @@
+package providers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+)
+
+// GenParams are the sampling parameters sent with each request. Nil/zero
+// fields inherit from the next level: model → provider → defaultGenParams.
+type GenParams struct {
+	Temperature *float64
+	MaxTokens   int
+}
+
+// defaultTemperature and defaultMaxTokens apply when neither the model nor the
+// provider configures them.
+const (
+	defaultTemperature = 0.7
+	defaultMaxTokens   = 8192
+)
+
+// Settings configures a provider instance; see the config package for the
+// file format. Zero fields fall back to the provider's built-in defaults.
+type Settings struct {
+	// APIKey and APIKeys together form the provider's key pool; requests
+	// rotate through them per KeySelection (KeyRoundRobin by default).
+	APIKey       string
+	APIKeys      []string
+	KeySelection string
+	BaseURL      string
+	DefaultModel string
+	// Models is the list exposed through GET /models. With ModelsTTL set it
+	// only supplies display names and capabilities for discovered models,
+	// and is the fallback while discovery fails.
+	Models []ModelInfo
+	// ModelsTTL enables model discovery from the provider's listing endpoint,
+	// caching the result for this long. Zero keeps Models as is.
+	ModelsTTL time.Duration
+	// Params apply to every model; ModelParams override them per model ID.
+	Params      GenParams
+	ModelParams map[string]GenParams
+	// Timeout bounds a whole HTTP exchange with the provider, including
+	// reading a streamed body. Zero means no per-provider limit beyond the
+	// request context (BR-003).
+	Timeout time.Duration
+	// Transport, if set, carries the provider's HTTP requests; a Cassette
+	// records or replays them.
+	Transport http.RoundTripper
+}
+
+// withDefaults fills zero fields of s from def.
+func (s Settings) withDefaults(def Settings) Settings {
+	if s.BaseURL == "" {
+		s.BaseURL = def.BaseURL
+	}
+	if s.DefaultModel == "" {
+		s.DefaultModel = def.DefaultModel
+	}
+	if len(s.Models) == 0 {
+		s.Models = def.Models
+	}
+	if s.Timeout == 0 {
+		s.Timeout = def.Timeout
+	}
+	return s
+}
+
+// model returns the model to call for req: the pinned model when the caller
+// pinned this provider, otherwise the default.
+func (s Settings) model(req models.GenerationRequest, provider string) string {
+	if req.PreferredModel != "" && req.PreferredProvider == provider {
+		return req.PreferredModel
+	}
+	return s.DefaultModel
+}
+
+// params resolves the sampling parameters for model.
+func (s Settings) params(model string) (temperature float64, maxTokens int) {
+	temperature, maxTokens = defaultTemperature, defaultMaxTokens
+	for _, p := range []GenParams{s.Params, s.ModelParams[model]} {
+		if p.Temperature != nil {
+			temperature = *p.Temperature
+		}
+		if p.MaxTokens > 0 {
+			maxTokens = p.MaxTokens
+		}
+	}
+	return temperature, maxTokens
+}
+
+// keyPool returns a new pool of APIKey and APIKeys.
+func (s Settings) keyPool() *KeyPool {
+	return NewKeyPool(append([]string{s.APIKey}, s.APIKeys...), s.KeySelection)
+}
+
+// httpClient returns a client honoring Timeout and Transport.
+func (s Settings) httpClient() *http.Client {
+	return &http.Client{Timeout: s.Timeout, Transport: s.Transport}
+}
@@ -0,0 +1,200 @@
+package providers
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Routing strategies.
+const (
+	// StrategyStatic tries providers in configured order (BR-005).
+	StrategyStatic = "static"
+	// StrategyAdaptive orders providers by observed health, keeping the
+	// configured order between providers that are equally healthy.
+	StrategyAdaptive = "adaptive"
+)
+
+const (
+	// statsAlpha weights the newest sample in the moving averages; 0.2 means
+	// roughly the last ten attempts dominate.
+	statsAlpha = 0.2
+	// statsWindow is how many recent latencies p50/p95 are computed from.
+	statsWindow = 100
+	// minHealthSamples is how many attempts a provider needs before its
+	// health affects ordering; until then it is treated as fully healthy.
+	minHealthSamples = 5
+	// healthLatencyScale is the p95 latency that halves a provider's health
+	// score.
+	healthLatencyScale = 10 * time.Second
+	// healthBucket rounds health scores so near-equal providers keep their
+	// configured order.
+	healthBucket = 0.05
+)
+
+// ProviderStats is a snapshot of one provider's (or provider/model's)
+// observed performance, exposed through GET /providers/stats.
+type ProviderStats struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+	Attempts int    `json:"attempts"`
+	Failures int    `json:"failures"`
+	// SuccessRate is an exponentially weighted moving average in [0, 1].
+	SuccessRate float64 `json:"success_rate"`
+	// LatencyMs is the EWMA latency; P50Ms and P95Ms are computed over the
+	// last statsWindow attempts.
+	LatencyMs float64 `json:"latency_ms"`
+	P50Ms     int64   `json:"p50_ms"`
+	P95Ms     int64   `json:"p95_ms"`
+	// Health is the score adaptive routing orders providers by.
+	Health   float64   `json:"health"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// series accumulates the samples for one provider or provider/model.
+type series struct {
+	attempts int
+	failures int
+	success  float64
+	latency  float64
+	window   []time.Duration // ring buffer
+	next     int
+	lastUsed time.Time
+}
+
+func (s *series) record(latency time.Duration, ok bool, at time.Time) {
+	v := 0.0
+	if ok {
+		v = 1
+	}
+	ms := float64(latency.Milliseconds())
+	if s.attempts == 0 {
+		s.success, s.latency = v, ms
+	} else {
+		s.success += statsAlpha * (v - s.success)
+		s.latency += statsAlpha * (ms - s.latency)
+	}
+	s.attempts++
+	if !ok {
+		s.failures++
+	}
+	if len(s.window) < statsWindow {
+		s.window = append(s.window, latency)
+	} else {
+		s.window[s.next] = latency
+		s.next = (s.next + 1) % statsWindow
+	}
+	s.lastUsed = at
+}
+
+// percentile returns the q-th quantile of the latency window.
+func (s *series) percentile(q float64) time.Duration {
+	if len(s.window) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(s.window)
+	slices.Sort(sorted)
+	return sorted[int(math.Ceil(q*float64(len(sorted))))-1]
+}
+
+// health scores the series in [0, 1]: the success rate, discounted by p95
+// latency. Series with too few samples score 1 so new providers get traffic.
+func (s *series) health() float64 {
+	if s == nil || s.attempts < minHealthSamples {
+		return 1
+	}
+	p95 := float64(s.percentile(0.95))
+	return s.success / (1 + p95/float64(healthLatencyScale))
+}
+
+func (s *series) snapshot(provider, model string) ProviderStats {
+	return ProviderStats{
+		Provider:    provider,
+		Model:       model,
+		Attempts:    s.attempts,
+		Failures:    s.failures,
+		SuccessRate: s.success,
+		LatencyMs:   math.Round(s.latency),
+		P50Ms:       s.percentile(0.5).Milliseconds(),
+		P95Ms:       s.percentile(0.95).Milliseconds(),
+		Health:      math.Round(s.health()*1000) / 1000,
+		LastUsed:    s.lastUsed,
+	}
+}
+
+// Stats tracks per-provider and per-model attempt outcomes and latency.
+type Stats struct {
+	mu        sync.Mutex
+	providers map[string]*series
+	models    map[[2]string]*series
+	now       func() time.Time
+}
+
+// NewStats creates an empty Stats.
+func NewStats() *Stats {
+	return &Stats{
+		providers: make(map[string]*series),
+		models:    make(map[[2]string]*series),
+		now:       time.Now,
+	}
+}
+
+// Record adds one attempt outcome for provider and model.
+func (s *Stats) Record(provider, model string, latency time.Duration, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	at := s.now()
+	p := s.providers[provider]
+	if p == nil {
+		p = &series{}
+		s.providers[provider] = p
+	}
+	p.record(latency, ok, at)
+
+	key := [2]string{provider, model}
+	m := s.models[key]
+	if m == nil {
+		m = &series{}
+		s.models[key] = m
+	}
+	m.record(latency, ok, at)
+}
+
+// Snapshot returns the provider-level and model-level stats, sorted by name.
+func (s *Stats) Snapshot() (providers, models []ProviderStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, ser := range s.providers {
+		providers = append(providers, ser.snapshot(name, ""))
+	}
+	for key, ser := range s.models {
+		models = append(models, ser.snapshot(key[0], key[1]))
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Provider < providers[j].Provider })
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].Provider != models[j].Provider {
+			return models[i].Provider < models[j].Provider
+		}
+		return models[i].Model < models[j].Model
+	})
+	return providers, models
+}
+
+// rank returns ps ordered by bucketed health, highest first. The sort is
+// stable, so equally healthy providers keep their configured order.
+func (s *Stats) rank(ps []Provider) []Provider {
+	s.mu.Lock()
+	scores := make(map[string]float64, len(ps))
+	for _, p := range ps {
+		scores[p.Name()] = math.Round(s.providers[p.Name()].health() / healthBucket)
+	}
+	s.mu.Unlock()
+
+	out := slices.Clone(ps)
+	sort.SliceStable(out, func(i, j int) bool {
+		return scores[out[i].Name()] > scores[out[j].Name()]
+	})
+	return out
+}
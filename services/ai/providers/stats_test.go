@@ -0,0 +1,75 @@
+package providers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/models"
+	"github.com/zest-app/ai-service/providers"
+)
+
+func TestStats_SnapshotTracksRatesAndPercentiles(t *testing.T) {
+	s := providers.NewStats()
+	for i := 1; i <= 10; i++ {
+		s.Record("gemini", "gemini-2.5-flash", time.Duration(i)*100*time.Millisecond, i != 10)
+	}
+	s.Record("glm", "glm-4.5-air", time.Second, true)
+
+	ps, ms := s.Snapshot()
+	if len(ps) != 2 || len(ms) != 2 {
+		t.Fatalf("got %d providers, %d models", len(ps), len(ms))
+	}
+	g := ps[0]
+	if g.Provider != "gemini" || g.Attempts != 10 || g.Failures != 1 {
+		t.Errorf("unexpected counts: %+v", g)
+	}
+	if g.P50Ms != 500 || g.P95Ms != 1000 {
+		t.Errorf("p50=%d p95=%d, want 500 and 1000", g.P50Ms, g.P95Ms)
+	}
+	// The failure was the most recent sample, so the EWMA sits well below
+	// the 90% plain success ratio.
+	if g.SuccessRate >= 0.9 || g.SuccessRate <= 0.7 {
+		t.Errorf("success rate = %v", g.SuccessRate)
+	}
+	if ms[0].Model != "gemini-2.5-flash" {
+		t.Errorf("unexpected model stats: %+v", ms[0])
+	}
+}
+
+func TestRoute_AdaptiveStrategyPrefersHealthyProvider(t *testing.T) {
+	flaky := &fakeProvider{name: "a", enabled: true, err: errors.New("boom")}
+	healthy := &fakeProvider{name: "b", enabled: true, out: "<html></html>"}
+	r := providers.NewRouter(flaky, healthy).
+		WithBreakerConfig(providers.BreakerConfig{FailureThreshold: 100}).
+		WithStrategy(providers.StrategyAdaptive)
+
+	for i := 0; i < 5; i++ {
+		if _, err := r.Route(context.Background(), models.GenerationRequest{}); err != nil {
+			t.Fatalf("route %d: %v", i, err)
+		}
+	}
+	if flaky.calls != 5 {
+		t.Fatalf("expected a to be tried first while it has too few samples, got %d calls", flaky.calls)
+	}
+
+	resp, err := r.Route(context.Background(), models.GenerationRequest{})
+	if err != nil || resp.Provider != "b" {
+		t.Fatalf("got provider %q, err %v", resp.Provider, err)
+	}
+	if flaky.calls != 5 {
+		t.Errorf("expected unhealthy a to be ordered after b, a called %d times", flaky.calls)
+	}
+
+	st := r.Stats()
+	if st.Strategy != providers.StrategyAdaptive || len(st.Providers) != 2 || st.Providers[0].Failures != 5 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+
+	// A preferred provider still goes first.
+	r.Route(context.Background(), models.GenerationRequest{PreferredProvider: "a"})
+	if flaky.calls != 6 {
+		t.Errorf("expected the preferred provider to be tried first")
+	}
+}
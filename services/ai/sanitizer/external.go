@@ -0,0 +1,206 @@
+package sanitizer
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// PlaceholderImage replaces remote images (BR-011). It is a neutral grey SVG
+// that scales to whatever box the original image occupied.
+const PlaceholderImage = `data:image/svg+xml,%3Csvg xmlns='http://www.w3.org/2000/svg' width='400' height='300' viewBox='0 0 400 300' preserveAspectRatio='none'%3E%3Crect width='400' height='300' fill='%23e5e7eb'/%3E%3Cpath d='M150 200l40-50 30 35 20-25 50 40z' fill='%239ca3af'/%3E%3Ccircle cx='170' cy='120' r='15' fill='%239ca3af'/%3E%3C/svg%3E`
+
+// TailwindCDN is allowed automatically in tailwind mode, where the generated
+// page depends on it for styling. Only stylesheet references benefit: a
+// <script src> to the Play CDN is still removed by Sanitize (BR-010).
+const TailwindCDN = "https://cdn.tailwindcss.com"
+
+var (
+	// reCSSImport matches @import rules in either url() or string form.
+	reCSSImport = regexp.MustCompile(`(?i)@import\s+(?:url\(\s*)?['"]?([^'")\s;]+)['"]?\s*\)?[^;]*;?`)
+	// reCSSFontFace matches a whole @font-face block (no nested braces in CSS).
+	reCSSFontFace = regexp.MustCompile(`(?is)@font-face\s*\{[^}]*\}`)
+	// reCSSURL matches url() references and captures the URL.
+	reCSSURL = regexp.MustCompile(`(?i)url\(\s*['"]?([^'")]+?)['"]?\s*\)`)
+)
+
+// StripExternal removes or replaces references to remote hosts that are not in
+// allowed (BR-011): stylesheet/preload <link>s are dropped, images and posters
+// become PlaceholderImage, media sources are removed, and CSS in <style> blocks
+// and style attributes is cleaned with StripExternalCSS.
+//
+// allowed entries may be origins ("https://fonts.googleapis.com") or bare hosts
+// ("fonts.gstatic.com").
+func StripExternal(src string, allowed []string) (string, Report) {
+	var report Report
+
+	root, isDoc, err := parse(src)
+	if err != nil {
+		return src, report
+	}
+	a := newAllowList(allowed)
+	stripExternalNode(root, a, &report)
+	if len(report.Removed) == 0 {
+		return src, report
+	}
+
+	out, err := render(root, isDoc)
+	if err != nil {
+		return src, report
+	}
+	return out, report
+}
+
+// StripExternalCSS removes @import rules and @font-face blocks that load from
+// remote hosts not in allowed, and replaces other remote url() references with
+// PlaceholderImage.
+func StripExternalCSS(css string, allowed []string) (string, Report) {
+	var report Report
+	return stripExternalCSS(css, newAllowList(allowed), &report), report
+}
+
+func stripExternalCSS(css string, a allowList, report *Report) string {
+	css = reCSSImport.ReplaceAllStringFunc(css, func(rule string) string {
+		u := reCSSImport.FindStringSubmatch(rule)[1]
+		if !a.blocks(u) {
+			return rule
+		}
+		report.add("@import %s", u)
+		return ""
+	})
+	css = reCSSFontFace.ReplaceAllStringFunc(css, func(block string) string {
+		for _, m := range reCSSURL.FindAllStringSubmatch(block, -1) {
+			if a.blocks(m[1]) {
+				report.add("@font-face %s", m[1])
+				return ""
+			}
+		}
+		return block
+	})
+	return reCSSURL.ReplaceAllStringFunc(css, func(ref string) string {
+		u := reCSSURL.FindStringSubmatch(ref)[1]
+		if !a.blocks(u) {
+			return ref
+		}
+		report.add("url() %s", u)
+		return `url("` + PlaceholderImage + `")`
+	})
+}
+
+// stripExternalNode cleans n and its descendants in place.
+func stripExternalNode(n *html.Node, a allowList, report *Report) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && c.DataAtom == atom.Link && a.blocks(attr(c, "href")) {
+			report.add("<link> %s", attr(c, "href"))
+			n.RemoveChild(c)
+		} else {
+			stripExternalNode(c, a, report)
+		}
+		c = next
+	}
+
+	if n.Type != html.ElementNode {
+		return
+	}
+	if n.DataAtom == atom.Style && n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+		n.FirstChild.Data = stripExternalCSS(n.FirstChild.Data, a, report)
+	}
+
+	kept := n.Attr[:0]
+	for _, at := range n.Attr {
+		key := strings.ToLower(at.Key)
+		switch {
+		case key == "style":
+			at.Val = stripExternalCSS(at.Val, a, report)
+		case key == "srcset" && srcsetBlocked(at.Val, a):
+			report.add("srcset on <%s>", n.Data)
+			continue
+		case isResourceAttr(n, at) && a.blocks(at.Val):
+			report.add("<%s> %s", n.Data, at.Val)
+			if !isImageAttr(n, key) {
+				// <video>, <audio>, <source>, <track>: no sensible placeholder.
+				continue
+			}
+			at.Val = PlaceholderImage
+		}
+		kept = append(kept, at)
+	}
+	n.Attr = kept
+}
+
+// isResourceAttr reports whether at makes the browser fetch a resource.
+// <a href> is navigation, not a fetch, and is left alone.
+func isResourceAttr(n *html.Node, at html.Attribute) bool {
+	switch strings.ToLower(at.Key) {
+	case "src", "poster", "background":
+		return true
+	case "href":
+		// SVG <image href> / <image xlink:href>
+		return n.Namespace == "svg" && n.Data == "image"
+	}
+	return false
+}
+
+// isImageAttr reports whether the resource is an image that can be swapped for
+// PlaceholderImage.
+func isImageAttr(n *html.Node, key string) bool {
+	return n.DataAtom == atom.Img || n.Data == "image" || key == "poster" || key == "background"
+}
+
+// srcsetBlocked reports whether any candidate in a srcset is a blocked URL.
+func srcsetBlocked(srcset string, a allowList) bool {
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 && a.blocks(fields[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// allowList is a set of lowercase hostnames that may be referenced.
+type allowList map[string]bool
+
+func newAllowList(entries []string) allowList {
+	a := make(allowList, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(strings.ToLower(e))
+		if e == "" {
+			continue
+		}
+		if u, err := url.Parse(e); err == nil && u.Host != "" {
+			a[u.Hostname()] = true
+			continue
+		}
+		a[strings.TrimSuffix(e, "/")] = true
+	}
+	return a
+}
+
+// blocks reports whether ref points at a remote host that is not allowed.
+// Relative URLs, fragments and data: URIs are always local.
+func (a allowList) blocks(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	lower := strings.ToLower(ref)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "//") {
+		return false
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.Host == "" {
+		return true
+	}
+	return !a[strings.ToLower(u.Hostname())]
+}
@@ -0,0 +1,84 @@
+package sanitizer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zest-app/ai-service/sanitizer"
+)
+
+func TestStripExternal_RemovesRemoteStylesheets(t *testing.T) {
+	in := `<!DOCTYPE html><html><head><link rel="stylesheet" href="https://cdn.example.com/x.css"><link rel="stylesheet" href="styles.css"></head><body></body></html>`
+
+	out, report := sanitizer.StripExternal(in, nil)
+
+	if strings.Contains(out, "cdn.example.com") {
+		t.Errorf("expected remote <link> to be removed, got:\n%s", out)
+	}
+	if !strings.Contains(out, `href="styles.css"`) {
+		t.Errorf("expected relative <link> to be kept, got:\n%s", out)
+	}
+	if len(report.Removed) != 1 {
+		t.Errorf("expected 1 removal, got %v", report.Removed)
+	}
+}
+
+func TestStripExternal_ReplacesRemoteImages(t *testing.T) {
+	in := `<img src="https://images.example.com/hero.jpg" srcset="https://images.example.com/hero@2x.jpg 2x" alt="Hero">`
+
+	out, _ := sanitizer.StripExternal(in, nil)
+
+	if strings.Contains(out, "images.example.com") {
+		t.Errorf("expected remote image to be replaced, got: %s", out)
+	}
+	if !strings.Contains(out, "data:image/svg+xml") || !strings.Contains(out, `alt="Hero"`) {
+		t.Errorf("expected placeholder image with original alt, got: %s", out)
+	}
+}
+
+func TestStripExternal_HonorsAllowList(t *testing.T) {
+	in := `<link rel="stylesheet" href="https://fonts.googleapis.com/css2?family=Inter"><img src="//evil.example/x.png">`
+
+	out, report := sanitizer.StripExternal(in, []string{"https://fonts.googleapis.com"})
+
+	if !strings.Contains(out, "fonts.googleapis.com") {
+		t.Errorf("expected allowed origin to be kept, got: %s", out)
+	}
+	if strings.Contains(out, "evil.example") {
+		t.Errorf("expected protocol-relative image to be replaced, got: %s", out)
+	}
+	if len(report.Removed) != 1 {
+		t.Errorf("expected 1 change, got %v", report.Removed)
+	}
+}
+
+func TestStripExternalCSS_RemovesImportsAndFonts(t *testing.T) {
+	css := `@import url("https://fonts.googleapis.com/css2?family=Inter");
+@font-face { font-family: X; src: url(https://fonts.example.com/x.woff2); }
+.hero { background: url('https://images.example.com/bg.jpg') center; }
+.local { background: url(bg.png); }`
+
+	out, report := sanitizer.StripExternalCSS(css, nil)
+
+	for _, host := range []string{"fonts.googleapis.com", "fonts.example.com", "images.example.com"} {
+		if strings.Contains(out, host) {
+			t.Errorf("expected %s to be stripped, got:\n%s", host, out)
+		}
+	}
+	if !strings.Contains(out, "url(bg.png)") {
+		t.Errorf("expected local url() to be kept, got:\n%s", out)
+	}
+	if len(report.Removed) != 3 {
+		t.Errorf("expected 3 changes, got %v", report.Removed)
+	}
+}
+
+func TestStripExternalCSS_AllowsBareHost(t *testing.T) {
+	css := `@import url("https://fonts.googleapis.com/css2?family=Inter");`
+
+	out, report := sanitizer.StripExternalCSS(css, []string{"fonts.googleapis.com"})
+
+	if out != css || len(report.Removed) != 0 {
+		t.Errorf("expected allowed import to be kept, got %q (%v)", out, report.Removed)
+	}
+}
@@ -0,0 +1,261 @@
+// Package sanitizer removes executable content from generated HTML/CSS (BR-010).
+//
+// Unlike the regex extraction in normalizer, sanitization works on a parsed DOM
+// (golang.org/x/net/html) so that obfuscated markup such as mixed-case tags,
+// unquoted attributes or scripts nested inside SVG cannot slip through.
+package sanitizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// removedElements are dropped together with their entire subtree.
+var removedElements = map[string]bool{
+	"script":   true,
+	"iframe":   true,
+	"frame":    true,
+	"frameset": true,
+	"object":   true,
+	"embed":    true,
+	"applet":   true,
+	"base":     true,
+	"portal":   true,
+}
+
+// urlAttributes may carry a navigable or loadable URL.
+var urlAttributes = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+	"data":       true,
+	"poster":     true,
+	"background": true,
+	"xlink:href": true,
+	"srcdoc":     true,
+}
+
+// animationElements are the SVG elements that can set an attribute of their
+// parent, e.g. <set attributeName="href" to="javascript:...">.
+var animationElements = map[string]bool{
+	"animate":          true,
+	"set":              true,
+	"animatemotion":    true,
+	"animatetransform": true,
+}
+
+var (
+	// reCSSScriptURL matches url(javascript:...) and friends inside CSS.
+	reCSSScriptURL = regexp.MustCompile(`(?i)url\(\s*['"]?\s*(?:javascript|vbscript):[^)]*\)`)
+	// reCSSExpression matches legacy IE expression() and behavior: bindings.
+	reCSSExpression = regexp.MustCompile(`(?i)expression\s*\(|behavior\s*:|-moz-binding\s*:`)
+	// reIsDocument detects a full document rather than a body fragment.
+	reIsDocument = regexp.MustCompile(`(?i)^\s*(?:<!doctype|<html[\s>])`)
+)
+
+// Report lists what was stripped, one human-readable entry per removal,
+// e.g. "<script>", "onclick on <button>", "javascript: URL in href on <a>".
+type Report struct {
+	Removed []string
+}
+
+func (r *Report) add(format string, args ...any) {
+	r.Removed = append(r.Removed, fmt.Sprintf(format, args...))
+}
+
+// Sanitize strips scripts, plugin/frame elements, inline event handlers and
+// script URLs from an HTML document or fragment and returns the re-serialized
+// markup. Input that fails to parse is returned unchanged with an empty report;
+// x/net/html follows the HTML5 error-recovery rules so this only happens for
+// pathological input.
+func Sanitize(src string) (string, Report) {
+	var report Report
+
+	root, isDoc, err := parse(src)
+	if err != nil {
+		return src, report
+	}
+	sanitizeNode(root, &report)
+	if len(report.Removed) == 0 {
+		// Nothing to strip — keep the LLM's original formatting.
+		return src, report
+	}
+
+	out, err := render(root, isDoc)
+	if err != nil {
+		return src, report
+	}
+	return out, report
+}
+
+// SanitizePartial is Sanitize for output that is still being generated. The
+// result is always re-serialized from the parsed tree, so markup cut off at
+// the end of src, such as a half-written tag, is dropped instead of passed
+// through. Input that fails to parse yields "".
+func SanitizePartial(src string) (string, Report) {
+	var report Report
+
+	root, isDoc, err := parse(src)
+	if err != nil {
+		return "", report
+	}
+	sanitizeNode(root, &report)
+	out, err := render(root, isDoc)
+	if err != nil {
+		return "", report
+	}
+	return out, report
+}
+
+// SanitizeCSS neutralizes script URLs and expression()-style bindings in a
+// stylesheet. It is intentionally narrow: CSS cannot run JavaScript in modern
+// browsers except through these legacy hooks.
+func SanitizeCSS(css string) (string, Report) {
+	var report Report
+	if reCSSScriptURL.MatchString(css) {
+		css = reCSSScriptURL.ReplaceAllString(css, "none")
+		report.add("script URL in stylesheet")
+	}
+	if reCSSExpression.MatchString(css) {
+		css = reCSSExpression.ReplaceAllString(css, "invalid-")
+		report.add("expression() in stylesheet")
+	}
+	return css, report
+}
+
+// sanitizeNode cleans n and its descendants in place.
+func sanitizeNode(n *html.Node, report *Report) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && removedElements[c.Data] {
+			report.add("<%s>", c.Data)
+			n.RemoveChild(c)
+		} else if isMetaRefresh(c) {
+			report.add("<meta http-equiv=refresh>")
+			n.RemoveChild(c)
+		} else if target := animatedURLAttribute(c); target != "" {
+			report.add("<%s> of %s", c.Data, target)
+			n.RemoveChild(c)
+		} else {
+			sanitizeNode(c, report)
+		}
+		c = next
+	}
+
+	if n.Type != html.ElementNode {
+		return
+	}
+	if n.DataAtom == atom.Style && n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+		css, r := SanitizeCSS(n.FirstChild.Data)
+		n.FirstChild.Data = css
+		report.Removed = append(report.Removed, r.Removed...)
+	}
+
+	kept := n.Attr[:0]
+	for _, a := range n.Attr {
+		key := strings.ToLower(a.Key)
+		if a.Namespace != "" {
+			key = a.Namespace + ":" + key
+		}
+		switch {
+		case strings.HasPrefix(key, "on"):
+			report.add("%s on <%s>", key, n.Data)
+			continue
+		case urlAttributes[key] && isScriptURL(a.Val):
+			report.add("script URL in %s on <%s>", key, n.Data)
+			continue
+		case key == "style" && (reCSSScriptURL.MatchString(a.Val) || reCSSExpression.MatchString(a.Val)):
+			report.add("script in style on <%s>", n.Data)
+			continue
+		}
+		kept = append(kept, a)
+	}
+	n.Attr = kept
+}
+
+// isScriptURL reports whether a URL attribute would execute code. Browsers
+// ignore embedded whitespace and control characters in the scheme, so those are
+// stripped before comparison.
+func isScriptURL(val string) bool {
+	v := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(val))
+	return strings.HasPrefix(v, "javascript:") ||
+		strings.HasPrefix(v, "vbscript:") ||
+		strings.HasPrefix(v, "data:text/html")
+}
+
+// animatedURLAttribute returns the URL attribute an SVG animation element
+// targets, or "" if it animates something else. Such elements are dropped
+// outright: their to/from/values/by may smuggle a script URL past the
+// attribute checks.
+func animatedURLAttribute(n *html.Node) string {
+	if n.Type != html.ElementNode || !animationElements[strings.ToLower(n.Data)] {
+		return ""
+	}
+	for _, a := range n.Attr {
+		if !strings.EqualFold(a.Key, "attributeName") {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(a.Val))
+		if urlAttributes[name] || strings.HasSuffix(name, ":href") {
+			return name
+		}
+	}
+	return ""
+}
+
+func isMetaRefresh(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.DataAtom != atom.Meta {
+		return false
+	}
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, "http-equiv") && strings.EqualFold(strings.TrimSpace(a.Val), "refresh") {
+			return true
+		}
+	}
+	return false
+}
+
+// parse parses src as a full document when it starts with a doctype or <html>
+// tag, otherwise as a fragment in a <body> context. Fragment nodes are attached
+// to a synthetic document node so both cases can be walked the same way.
+func parse(src string) (*html.Node, bool, error) {
+	if reIsDocument.MatchString(src) {
+		doc, err := html.Parse(strings.NewReader(src))
+		return doc, true, err
+	}
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(src), body)
+	if err != nil {
+		return nil, false, err
+	}
+	root := &html.Node{Type: html.DocumentNode}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	return root, false, nil
+}
+
+// render serializes a tree produced by parse back to markup.
+func render(root *html.Node, isDoc bool) (string, error) {
+	var sb strings.Builder
+	if isDoc {
+		err := html.Render(&sb, root)
+		return sb.String(), err
+	}
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&sb, c); err != nil {
+			return "", err
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,114 @@
+package sanitizer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zest-app/ai-service/sanitizer"
+)
+
+func TestSanitize_RemovesScriptTags(t *testing.T) {
+	in := `<!DOCTYPE html><html><head><script>alert(1)</script></head><body><h1>Hi</h1><SCRIPT src="x.js"></SCRIPT></body></html>`
+
+	out, report := sanitizer.Sanitize(in)
+
+	if strings.Contains(strings.ToLower(out), "<script") {
+		t.Errorf("expected scripts to be removed, got:\n%s", out)
+	}
+	if !strings.Contains(out, "<h1>Hi</h1>") {
+		t.Errorf("expected content to be preserved, got:\n%s", out)
+	}
+	if len(report.Removed) != 2 {
+		t.Errorf("expected 2 removals, got %v", report.Removed)
+	}
+}
+
+func TestSanitize_RemovesEventHandlers(t *testing.T) {
+	out, report := sanitizer.Sanitize(`<button onclick="steal()" class="btn" ONMOUSEOVER=x>Go</button>`)
+
+	if strings.Contains(strings.ToLower(out), "onclick") || strings.Contains(strings.ToLower(out), "onmouseover") {
+		t.Errorf("expected event handlers to be removed, got: %s", out)
+	}
+	if !strings.Contains(out, `class="btn"`) {
+		t.Errorf("expected other attributes to be kept, got: %s", out)
+	}
+	if len(report.Removed) != 2 {
+		t.Errorf("expected 2 removals, got %v", report.Removed)
+	}
+}
+
+func TestSanitize_RemovesScriptURLs(t *testing.T) {
+	out, _ := sanitizer.Sanitize(`<a href=" java&#09;script:alert(1)">x</a><a href="/about">ok</a>`)
+
+	if strings.Contains(out, "script:") {
+		t.Errorf("expected javascript: URL to be removed, got: %s", out)
+	}
+	if !strings.Contains(out, `href="/about"`) {
+		t.Errorf("expected safe href to be kept, got: %s", out)
+	}
+}
+
+func TestSanitize_RemovesSVGAnimationsOfLinks(t *testing.T) {
+	in := `<svg><a><set attributeName="href" to="javascript:alert(1)"/>` +
+		`<animate attributeName="xlink:href" values="#;javascript:alert(2)"/><text>x</text></a>` +
+		`<circle r="5"><animate attributeName="r" from="5" to="10" dur="1s"/></circle></svg>`
+
+	out, report := sanitizer.Sanitize(in)
+
+	if strings.Contains(out, "javascript:") {
+		t.Errorf("expected href animations to be removed, got: %s", out)
+	}
+	if !strings.Contains(out, `attributeName="r"`) {
+		t.Errorf("expected other animations to be kept, got: %s", out)
+	}
+	if len(report.Removed) != 2 {
+		t.Errorf("expected 2 removals, got %v", report.Removed)
+	}
+}
+
+func TestSanitize_RemovesEmbeddingElements(t *testing.T) {
+	in := `<div><iframe src="https://evil.example"></iframe><object data="x.swf"></object><embed src="x"><p>ok</p></div>`
+
+	out, report := sanitizer.Sanitize(in)
+
+	for _, tag := range []string{"<iframe", "<object", "<embed"} {
+		if strings.Contains(out, tag) {
+			t.Errorf("expected %s to be removed, got: %s", tag, out)
+		}
+	}
+	if len(report.Removed) != 3 {
+		t.Errorf("expected 3 removals, got %v", report.Removed)
+	}
+}
+
+func TestSanitize_CleanInputUnchanged(t *testing.T) {
+	in := "<section>\n  <h1>Coffee</h1>\n</section>"
+
+	out, report := sanitizer.Sanitize(in)
+
+	if out != in {
+		t.Errorf("expected clean input to be returned verbatim, got:\n%s", out)
+	}
+	if len(report.Removed) != 0 {
+		t.Errorf("expected empty report, got %v", report.Removed)
+	}
+}
+
+func TestSanitizeCSS_NeutralizesScriptURLs(t *testing.T) {
+	out, report := sanitizer.SanitizeCSS(`body { background: url("javascript:alert(1)"); width: expression(alert(1)); }`)
+
+	if strings.Contains(out, "javascript:") || strings.Contains(out, "expression(") {
+		t.Errorf("expected script constructs to be neutralized, got: %s", out)
+	}
+	if len(report.Removed) != 2 {
+		t.Errorf("expected 2 removals, got %v", report.Removed)
+	}
+}
+
+func TestSanitizePartial_DropsTagCutOffAtTheEnd(t *testing.T) {
+	out, _ := sanitizer.SanitizePartial(`<p>Hello</p><img src=x onerror=alert(1)`)
+
+	if strings.Contains(out, "onerror") || !strings.Contains(out, "<p>Hello</p>") {
+		t.Errorf("unexpected partial output: %s", out)
+	}
+}
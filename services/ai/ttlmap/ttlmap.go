@@ -0,0 +1,170 @@
+// Package ttlmap is the expiring in-process map behind the memory stores of
+// packages cache, jobs and idempotency. Expired entries are dropped when read
+// and swept out periodically on writes, so keys that are never read again do
+// not accumulate.
+package ttlmap
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is the minimum time between two sweeps of expired entries.
+const sweepInterval = time.Minute
+
+// Map is a concurrency-safe map of string keys to values that expire.
+type Map[V any] struct {
+	mu         sync.Mutex
+	entries    map[string]entry[V]
+	now        func() time.Time
+	maxEntries int
+	lastSweep  time.Time
+}
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// New creates an empty, unbounded Map.
+func New[V any]() *Map[V] {
+	return &Map[V]{entries: make(map[string]entry[V]), now: time.Now}
+}
+
+// WithClock replaces the time source, allowing tests to expire entries
+// without sleeping.
+func (m *Map[V]) WithClock(now func() time.Time) *Map[V] {
+	m.now = now
+	return m
+}
+
+// WithMaxEntries bounds the map to n entries; when a write goes over, the
+// entries closest to expiry are evicted first. Non-positive n means no bound.
+func (m *Map[V]) WithMaxEntries(n int) *Map[V] {
+	m.maxEntries = n
+	return m
+}
+
+// Get returns the unexpired value under key.
+func (m *Map[V]) Get(key string) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.get(key)
+}
+
+// Set stores v under key for ttl, replacing any previous value.
+func (m *Map[V]) Set(key string, v V, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.set(key, v, ttl)
+}
+
+// SetNX stores v under key for ttl unless an unexpired value is already there.
+// It returns the value now under key and whether v was stored.
+func (m *Map[V]) SetNX(key string, v V, ttl time.Duration) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.get(key); ok {
+		return existing, false
+	}
+	m.set(key, v, ttl)
+	return v, true
+}
+
+// Update applies fn to the unexpired value under key and, if fn returns true,
+// stores the modified value for ttl. It returns the value, whether there was
+// one, and whether it was stored.
+func (m *Map[V]) Update(key string, ttl time.Duration, fn func(*V) bool) (v V, found, stored bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, found = m.get(key)
+	if !found || !fn(&v) {
+		return v, found, false
+	}
+	m.set(key, v, ttl)
+	return v, true, true
+}
+
+// DeleteFunc removes key if its unexpired value satisfies match, and reports
+// whether it did.
+func (m *Map[V]) DeleteFunc(key string, match func(V) bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, ok := m.get(key)
+	if !ok || !match(v) {
+		return false
+	}
+	delete(m.entries, key)
+	return true
+}
+
+// Len returns the number of stored entries, including expired ones that have
+// not been swept yet.
+func (m *Map[V]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.entries)
+}
+
+// get returns the unexpired value under key. The caller holds mu.
+func (m *Map[V]) get(key string) (V, bool) {
+	e, ok := m.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if !m.now().Before(e.expiresAt) {
+		delete(m.entries, key)
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// set stores v, then sweeps and evicts as needed. The caller holds mu.
+func (m *Map[V]) set(key string, v V, ttl time.Duration) {
+	now := m.now()
+	m.entries[key] = entry[V]{value: v, expiresAt: now.Add(ttl)}
+
+	if now.Sub(m.lastSweep) >= sweepInterval || m.over() {
+		m.sweep(now)
+	}
+	for m.over() {
+		m.evictSoonest()
+	}
+}
+
+func (m *Map[V]) over() bool {
+	return m.maxEntries > 0 && len(m.entries) > m.maxEntries
+}
+
+// sweep drops every expired entry. The caller holds mu.
+func (m *Map[V]) sweep(now time.Time) {
+	for k, e := range m.entries {
+		if !now.Before(e.expiresAt) {
+			delete(m.entries, k)
+		}
+	}
+	m.lastSweep = now
+}
+
+// evictSoonest drops the entry closest to expiry. The caller holds mu.
+func (m *Map[V]) evictSoonest() {
+	var (
+		victim  string
+		soonest time.Time
+		found   bool
+	)
+	for k, e := range m.entries {
+		if !found || e.expiresAt.Before(soonest) {
+			victim, soonest, found = k, e.expiresAt, true
+		}
+	}
+	delete(m.entries, victim)
+}
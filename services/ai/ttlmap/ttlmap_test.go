@@ -0,0 +1,53 @@
+package ttlmap_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/ttlmap"
+)
+
+func TestMap_SweepsKeysThatAreNeverReadAgain(t *testing.T) {
+	now := time.Unix(0, 0)
+	m := ttlmap.New[int]().WithClock(func() time.Time { return now })
+
+	for i, k := range []string{"a", "b", "c"} {
+		m.Set(k, i, time.Second)
+	}
+	now = now.Add(2 * time.Minute)
+	m.Set("d", 3, time.Hour)
+
+	if n := m.Len(); n != 1 {
+		t.Errorf("Len = %d after the sweep, want 1", n)
+	}
+}
+
+func TestMap_EvictsSoonestToExpireOverCapacity(t *testing.T) {
+	m := ttlmap.New[string]().WithMaxEntries(2)
+	m.Set("short", "x", time.Minute)
+	m.Set("long", "y", time.Hour)
+	m.Set("mid", "z", 30*time.Minute)
+
+	if _, ok := m.Get("short"); ok {
+		t.Error("expected the entry closest to expiry to be evicted")
+	}
+	if _, ok := m.Get("long"); !ok || m.Len() != 2 {
+		t.Errorf("Len = %d, want the two later entries kept", m.Len())
+	}
+}
+
+func TestMap_SetNXAndDeleteFunc(t *testing.T) {
+	m := ttlmap.New[string]()
+	if _, stored := m.SetNX("k", "first", time.Minute); !stored {
+		t.Fatal("expected the first SetNX to store")
+	}
+	if v, stored := m.SetNX("k", "second", time.Minute); stored || v != "first" {
+		t.Fatalf("SetNX = %q, %v; want the existing value", v, stored)
+	}
+	if m.DeleteFunc("k", func(v string) bool { return v == "second" }) {
+		t.Error("DeleteFunc removed a non-matching value")
+	}
+	if !m.DeleteFunc("k", func(v string) bool { return v == "first" }) {
+		t.Error("DeleteFunc kept a matching value")
+	}
+}
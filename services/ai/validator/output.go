@@ -0,0 +1,87 @@
+package validator
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/zest-app/ai-service/normalizer"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Errors returned by CheckOutput. The router treats any of them as a failed
+// provider attempt and falls back to the next provider.
+var (
+	ErrEmptyOutput = errors.New("empty output")
+	ErrNoHTML      = errors.New("output contains no HTML")
+	ErrRefusal     = errors.New("output is a refusal")
+	ErrEmptyBody   = errors.New("output has no body content")
+	ErrTruncated   = errors.New("output is truncated")
+)
+
+var (
+	// reHTMLTagStart matches the start of any tag, doctype or comment.
+	reHTMLTagStart = regexp.MustCompile(`<[a-zA-Z!]`)
+
+	// refusalPhrases are typical openings of an LLM declining a request.
+	refusalPhrases = []string{
+		"i'm sorry", "i am sorry", "i apologize", "sorry, but",
+		"i can't", "i cannot", "i can not", "i won't", "i will not",
+		"i'm unable", "i am unable", "as an ai",
+	}
+)
+
+// CheckOutput performs structural checks on a raw provider response before it
+// is accepted: it must contain HTML, must not open with a refusal, must have
+// visible body content and must not be cut off. It is the default
+// providers.OutputValidator.
+func CheckOutput(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ErrEmptyOutput
+	}
+
+	if !reHTMLTagStart.MatchString(raw) {
+		// A refusal opens the response. A caveat followed by a full page is
+		// judged by the structural checks below instead.
+		lower := strings.ToLower(raw)
+		for _, phrase := range refusalPhrases {
+			if strings.HasPrefix(lower, phrase) {
+				return ErrRefusal
+			}
+		}
+		return ErrNoHTML
+	}
+
+	parsed := normalizer.Parse(raw, "html_css")
+	if _, report := Repair(parsed.HTML); report.Truncated {
+		return ErrTruncated
+	}
+
+	doc, err := html.Parse(strings.NewReader(parsed.HTML))
+	if err != nil {
+		return err
+	}
+	if body := find(doc, atom.Body); body == nil || !hasContent(body) {
+		return ErrEmptyBody
+	}
+	return nil
+}
+
+// hasContent reports whether n contains any element or non-whitespace text.
+func hasContent(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.ElementNode:
+			if c.DataAtom != atom.Script && c.DataAtom != atom.Style {
+				return true
+			}
+		case html.TextNode:
+			if strings.TrimSpace(c.Data) != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
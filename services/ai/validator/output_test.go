@@ -0,0 +1,31 @@
+package validator_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zest-app/ai-service/validator"
+)
+
+func TestCheckOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want error
+	}{
+		{"complete page", "<!DOCTYPE html><html><head></head><body><h1>Hi</h1></body></html>", nil},
+		{"fenced page", "Here you go:\n```html\n<html><body><main>Hi</main></body></html>\n```", nil},
+		{"empty", "   \n", validator.ErrEmptyOutput},
+		{"prose", "Here is a description of a landing page with a hero section.", validator.ErrNoHTML},
+		{"refusal", "I'm sorry, but I can't help with that request.", validator.ErrRefusal},
+		{"empty body", "<html><head><style>body{}</style></head><body>  </body></html>", validator.ErrEmptyBody},
+		{"truncated", "<html><body><section><h1>Title</h1><div class=\"ca", validator.ErrTruncated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validator.CheckOutput(tt.raw); !errors.Is(err, tt.want) {
+				t.Errorf("CheckOutput() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Package validator checks that generated HTML is a well-formed HTML5 document
+// and repairs it when it is not (BR-009).
+//
+// LLM output is frequently cut off when a provider hits its max-token limit, so
+// besides fixing structure the validator reports whether the input looked
+// truncated; the Next.js side uses that to offer a "continue generation" action.
+package validator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// voidElements never have an end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// optionalEndTags may legitimately be left open in HTML5.
+var optionalEndTags = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true,
+	"dt": true, "dd": true, "option": true, "optgroup": true, "tr": true,
+	"td": true, "th": true, "thead": true, "tbody": true, "tfoot": true,
+	"colgroup": true, "rb": true, "rt": true, "rp": true,
+}
+
+// Report describes what Repair changed.
+type Report struct {
+	// Fixed lists each repair, e.g. "added <!DOCTYPE html>", "closed <section>".
+	Fixed []string
+	// Truncated is true when the input appears to have been cut off mid-document.
+	Truncated bool
+}
+
+func (r *Report) add(format string, args ...any) {
+	r.Fixed = append(r.Fixed, fmt.Sprintf(format, args...))
+}
+
+// Repair parses src with the HTML5 parsing algorithm and returns a complete
+// document with a doctype, <html>, <head> (including charset and viewport
+// meta tags) and <body>. Unclosed elements are closed and a partial trailing
+// tag is dropped. When nothing needed fixing, src is returned unchanged.
+func Repair(src string) (string, Report) {
+	var report Report
+
+	src, partial := trimPartialTag(src)
+	if partial != "" {
+		report.Truncated = true
+		report.add("removed incomplete trailing tag %q", partial)
+	}
+
+	seen := scan(src, &report)
+
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return src, report
+	}
+
+	if doc.FirstChild == nil || doc.FirstChild.Type != html.DoctypeNode {
+		doc.InsertBefore(&html.Node{Type: html.DoctypeNode, Data: "html"}, doc.FirstChild)
+		report.add("added <!DOCTYPE html>")
+	}
+	for _, tag := range []string{"html", "head", "body"} {
+		if !seen[tag] {
+			report.add("added <%s>", tag)
+		}
+	}
+	if head := find(doc, atom.Head); head != nil {
+		ensureMeta(head, &report)
+	}
+
+	if len(report.Fixed) == 0 {
+		return src, report
+	}
+
+	var sb strings.Builder
+	if err := html.Render(&sb, doc); err != nil {
+		return src, report
+	}
+	return sb.String(), report
+}
+
+// trimPartialTag cuts a tag or comment that was left open at the end of src,
+// e.g. `<div class="he`. It returns the trimmed source and the removed text.
+func trimPartialTag(src string) (string, string) {
+	trimmed := strings.TrimRight(src, " \t\r\n")
+	lt := strings.LastIndex(trimmed, "<")
+	if lt < 0 || lt < strings.LastIndex(trimmed, ">") {
+		return src, ""
+	}
+	return trimmed[:lt], trimmed[lt:]
+}
+
+// scan tokenizes src, recording unclosed and stray tags in report and marking
+// it truncated when non-optional elements are still open at the end. It
+// returns the set of start tags seen.
+func scan(src string, report *Report) map[string]bool {
+	seen := make(map[string]bool)
+	var stack []string
+
+	z := html.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				report.add("tokenizer error: %v", z.Err())
+			}
+			break
+		}
+		name, _ := z.TagName()
+		tag := string(name)
+		switch tt {
+		case html.StartTagToken:
+			seen[tag] = true
+			if !voidElements[tag] {
+				stack = append(stack, tag)
+			}
+		case html.SelfClosingTagToken:
+			seen[tag] = true
+		case html.EndTagToken:
+			seen["/"+tag] = true
+			i := len(stack) - 1
+			for i >= 0 && stack[i] != tag {
+				i--
+			}
+			if i < 0 {
+				if !voidElements[tag] {
+					report.add("removed stray </%s>", tag)
+				}
+				continue
+			}
+			for _, open := range stack[i+1:] {
+				if !optionalEndTags[open] {
+					report.add("closed <%s>", open)
+				}
+			}
+			stack = stack[:i]
+		}
+	}
+
+	for _, open := range stack {
+		if !optionalEndTags[open] {
+			report.add("closed <%s>", open)
+			report.Truncated = true
+		}
+	}
+	if seen["html"] && !seen["/html"] {
+		report.Truncated = true
+	}
+	return seen
+}
+
+// ensureMeta adds <meta charset="UTF-8"> and a viewport meta tag to head when
+// they are missing.
+func ensureMeta(head *html.Node, report *Report) {
+	hasCharset, hasViewport := false, false
+	for c := head.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.DataAtom != atom.Meta {
+			continue
+		}
+		for _, a := range c.Attr {
+			switch {
+			case a.Key == "charset":
+				hasCharset = true
+			case a.Key == "name" && strings.EqualFold(a.Val, "viewport"):
+				hasViewport = true
+			}
+		}
+	}
+
+	if !hasViewport {
+		head.InsertBefore(&html.Node{
+			Type: html.ElementNode, Data: "meta", DataAtom: atom.Meta,
+			Attr: []html.Attribute{
+				{Key: "name", Val: "viewport"},
+				{Key: "content", Val: "width=device-width, initial-scale=1"},
+			},
+		}, head.FirstChild)
+		report.add(`added <meta name="viewport">`)
+	}
+	if !hasCharset {
+		// charset must come first so it applies to the rest of the head.
+		head.InsertBefore(&html.Node{
+			Type: html.ElementNode, Data: "meta", DataAtom: atom.Meta,
+			Attr: []html.Attribute{{Key: "charset", Val: "UTF-8"}},
+		}, head.FirstChild)
+		report.add(`added <meta charset="UTF-8">`)
+	}
+}
+
+// find returns the first element with the given atom in depth-first order.
+func find(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := find(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
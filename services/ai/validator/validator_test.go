@@ -0,0 +1,80 @@
+package validator_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zest-app/ai-service/validator"
+)
+
+const complete = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Coffee</title>
+</head>
+<body>
+<section><h1>Coffee</h1></section>
+</body>
+</html>`
+
+func TestRepair_CompleteDocumentUnchanged(t *testing.T) {
+	out, report := validator.Repair(complete)
+
+	if out != complete {
+		t.Errorf("expected valid document to be returned verbatim, got:\n%s", out)
+	}
+	if len(report.Fixed) != 0 || report.Truncated {
+		t.Errorf("expected empty report, got %+v", report)
+	}
+}
+
+func TestRepair_WrapsFragment(t *testing.T) {
+	out, report := validator.Repair(`<section><h1>Hi</h1></section>`)
+
+	for _, want := range []string{"<!DOCTYPE html>", "<html>", "<head>", `<meta charset="UTF-8"/>`, `name="viewport"`, "<body>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %s, got:\n%s", want, out)
+		}
+	}
+	if report.Truncated {
+		t.Error("a complete fragment should not be reported as truncated")
+	}
+}
+
+func TestRepair_TruncatedDocument(t *testing.T) {
+	in := `<!DOCTYPE html><html><head><meta charset="UTF-8"><meta name="viewport" content="width=device-width"></head><body><section><h1>Title</h1><div class="car`
+
+	out, report := validator.Repair(in)
+
+	if !report.Truncated {
+		t.Error("expected document to be reported as truncated")
+	}
+	if strings.Contains(out, `class="car`) {
+		t.Errorf("expected partial trailing tag to be removed, got:\n%s", out)
+	}
+	if !strings.Contains(out, "</section></body></html>") {
+		t.Errorf("expected open elements to be closed, got:\n%s", out)
+	}
+	if !containsFix(report.Fixed, "closed <section>") {
+		t.Errorf("expected section close to be reported, got %v", report.Fixed)
+	}
+}
+
+func TestRepair_MissingClosingHTMLIsTruncated(t *testing.T) {
+	_, report := validator.Repair(strings.TrimSuffix(complete, "</body>\n</html>"))
+
+	if !report.Truncated {
+		t.Error("expected missing </html> to be reported as truncated")
+	}
+}
+
+func containsFix(fixed []string, want string) bool {
+	for _, f := range fixed {
+		if f == want {
+			return true
+		}
+	}
+	return false
+}
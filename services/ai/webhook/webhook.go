@@ -0,0 +1,336 @@
+// Package webhook POSTs signed generation results to caller-supplied
+// callback URLs.
+//
+// Each request carries
+//
+//	X-Zest-Event:     "job.succeeded" or "job.failed"
+//	X-Zest-Delivery:  a unique ID, the same across retries of one delivery
+//	X-Zest-Job-Id:    the job the result belongs to
+//	X-Zest-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+//
+// keyed with WEBHOOK_SECRET. Receivers should recompute the signature with
+// Verify and reject stale timestamps to prevent replays.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Request headers set on every delivery.
+const (
+	SignatureHeader = "X-Zest-Signature"
+	EventHeader     = "X-Zest-Event"
+	DeliveryHeader  = "X-Zest-Delivery"
+	JobHeader       = "X-Zest-Job-Id"
+)
+
+// Delivery statuses.
+const (
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// logSize bounds the in-memory delivery log.
+const logSize = 500
+
+// Config controls delivery retries.
+type Config struct {
+	// MaxAttempts is the total number of POSTs made before giving up.
+	MaxAttempts int
+	// BaseDelay doubles after every failed attempt, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Timeout bounds each POST.
+	Timeout time.Duration
+	// Sleep waits for d or until ctx is done. Tests inject a fake one.
+	// Defaults to a timer-based sleep.
+	Sleep func(ctx context.Context, d time.Duration) error
+	// AllowPrivate permits callbacks to loopback, link-local and private
+	// addresses, which are otherwise refused on every connection, redirects
+	// included, so a callback_url cannot reach internal services. For tests
+	// and local development only.
+	AllowPrivate bool
+}
+
+// DefaultConfig makes up to 5 attempts over roughly 15 seconds.
+var DefaultConfig = Config{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Timeout:     10 * time.Second,
+}
+
+// withDefaults fills zero fields from DefaultConfig.
+func (c Config) withDefaults() Config {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultConfig.MaxAttempts
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = DefaultConfig.BaseDelay
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = DefaultConfig.MaxDelay
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultConfig.Timeout
+	}
+	if c.Sleep == nil {
+		c.Sleep = sleepContext
+	}
+	return c
+}
+
+// Attempt records one POST of a delivery.
+type Attempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// Delivery is an entry of the delivery log.
+type Delivery struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	JobID     string    `json:"job_id"`
+	URL       string    `json:"url"`
+	Status    string    `json:"status"`
+	Attempts  []Attempt `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Notifier delivers signed callbacks and keeps a log of recent deliveries.
+type Notifier struct {
+	secret []byte
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+
+	mu  sync.Mutex
+	log []Delivery // oldest first, at most logSize
+}
+
+// NewNotifier creates a Notifier signing with secret.
+func NewNotifier(secret string, cfg Config) *Notifier {
+	cfg = cfg.withDefaults()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !cfg.AllowPrivate {
+		// A proxy would make the dialer check the proxy's address instead.
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{Timeout: cfg.Timeout, Control: publicOnly}).DialContext
+	}
+	return &Notifier{
+		secret: []byte(secret),
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		now:    time.Now,
+	}
+}
+
+// ValidateURL checks that u is an absolute http(s) URL and, unless private
+// addresses are allowed, that its host is not obviously internal. Hosts that
+// resolve to internal addresses are refused when delivering.
+func (n *Notifier) ValidateURL(u string) error {
+	parsed, err := parseURL(u)
+	if err != nil || n.cfg.AllowPrivate {
+		return err
+	}
+	host := parsed.Hostname()
+	if ip, err := netip.ParseAddr(host); (err == nil && !isPublic(ip)) || strings.EqualFold(host, "localhost") {
+		return fmt.Errorf("invalid callback_url %q: host is not a public address", u)
+	}
+	return nil
+}
+
+func parseURL(u string) (*neturl.URL, error) {
+	parsed, err := neturl.Parse(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid callback_url: %w", err)
+	}
+	if (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid callback_url %q: must be an absolute http(s) URL", u)
+	}
+	return parsed, nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which
+// netip does not count as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPublic reports whether ip is a globally routable unicast address.
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// publicOnly is a net.Dialer Control func refusing connections to
+// non-public addresses. It runs after DNS resolution, for every connection.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !isPublic(ip) {
+		return fmt.Errorf("webhook: refusing to connect to non-public address %s", host)
+	}
+	return nil
+}
+
+// Deliver POSTs payload as JSON to url, retrying with exponential backoff
+// until a 2xx response or MaxAttempts. It returns the logged Delivery.
+func (n *Notifier) Deliver(ctx context.Context, url, event, jobID string, payload any) Delivery {
+	d := Delivery{ID: uuid.New().String(), Event: event, JobID: jobID, URL: url, CreatedAt: n.now()}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.Status = StatusFailed
+		d.Attempts = append(d.Attempts, Attempt{At: n.now(), Error: "encode payload: " + err.Error()})
+		n.record(d)
+		return d
+	}
+
+	delay := n.cfg.BaseDelay
+	for i := 0; i < n.cfg.MaxAttempts; i++ {
+		if i > 0 {
+			if err := n.cfg.Sleep(ctx, delay); err != nil {
+				break
+			}
+			delay = min(2*delay, n.cfg.MaxDelay)
+		}
+		a := n.post(ctx, url, event, d.ID, jobID, body)
+		d.Attempts = append(d.Attempts, a)
+		if a.Error == "" {
+			d.Status = StatusDelivered
+			n.record(d)
+			return d
+		}
+		log.Printf("[ai-service] webhook %s for job %s: attempt %d: %s", d.ID, jobID, i+1, a.Error)
+	}
+	d.Status = StatusFailed
+	n.record(d)
+	return d
+}
+
+// post makes one signed POST.
+func (n *Notifier) post(ctx context.Context, url, event, deliveryID, jobID string, body []byte) Attempt {
+	start := n.now()
+	a := Attempt{At: start}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(JobHeader, jobID)
+	req.Header.Set(SignatureHeader, Sign(n.secret, start, body))
+
+	resp, err := n.client.Do(req)
+	a.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	resp.Body.Close()
+	a.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		a.Error = "unexpected status " + resp.Status
+	}
+	return a
+}
+
+// record appends d to the delivery log, dropping the oldest entry when full.
+func (n *Notifier) record(d Delivery) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.log) == logSize {
+		n.log = append(n.log[:0:0], n.log[1:]...)
+	}
+	n.log = append(n.log, d)
+}
+
+// Deliveries returns the delivery log, newest first.
+func (n *Notifier) Deliveries() []Delivery {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	out := make([]Delivery, len(n.log))
+	for i, d := range n.log {
+		out[len(n.log)-1-i] = d
+	}
+	return out
+}
+
+// Sign returns the SignatureHeader value for body sent at t.
+func Sign(secret []byte, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + mac(secret, ts, body)
+}
+
+// ErrBadSignature is returned by Verify for a missing, malformed, stale or
+// mismatched signature.
+var ErrBadSignature = errors.New("webhook: bad signature")
+
+// Verify checks header, a SignatureHeader value, against body. Signatures
+// older or newer than tolerance relative to now are rejected.
+func Verify(secret []byte, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return ErrBadSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrBadSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(mac(secret, ts, body))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+func mac(secret []byte, ts string, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(ts))
+	h.Write([]byte{'.'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
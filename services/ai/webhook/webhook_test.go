@@ -0,0 +1,126 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zest-app/ai-service/webhook"
+)
+
+const secret = "whsec-test"
+
+func noSleep(delays *[]time.Duration) func(context.Context, time.Duration) error {
+	return func(_ context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return nil
+	}
+}
+
+func TestDeliver_RetriesUntilAcceptedAndSigns(t *testing.T) {
+	var mu sync.Mutex
+	var deliveryIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.Verify([]byte(secret), r.Header.Get(webhook.SignatureHeader), body, time.Minute, time.Now()); err != nil {
+			t.Errorf("receiver: %v", err)
+		}
+		mu.Lock()
+		deliveryIDs = append(deliveryIDs, r.Header.Get(webhook.DeliveryHeader))
+		n := len(deliveryIDs)
+		mu.Unlock()
+		if r.Header.Get(webhook.JobHeader) != "job-1" || r.Header.Get(webhook.EventHeader) != "job.succeeded" {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	n := webhook.NewNotifier(secret, webhook.Config{BaseDelay: time.Second, Sleep: noSleep(&delays), AllowPrivate: true})
+	d := n.Deliver(context.Background(), srv.URL, "job.succeeded", "job-1", map[string]string{"status": "success"})
+
+	if d.Status != webhook.StatusDelivered || len(d.Attempts) != 3 || d.Attempts[2].StatusCode != http.StatusNoContent {
+		t.Fatalf("delivery = %+v", d)
+	}
+	if deliveryIDs[0] != d.ID || deliveryIDs[2] != d.ID {
+		t.Errorf("delivery ID changed across retries: %v", deliveryIDs)
+	}
+	if len(delays) != 2 || delays[0] != time.Second || delays[1] != 2*time.Second {
+		t.Errorf("backoff = %v, want [1s 2s]", delays)
+	}
+	if log := n.Deliveries(); len(log) != 1 || log[0].ID != d.ID {
+		t.Errorf("delivery log = %+v", log)
+	}
+}
+
+func TestDeliver_GivesUpAfterMaxAttempts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	n := webhook.NewNotifier(secret, webhook.Config{MaxAttempts: 3, Sleep: noSleep(&delays), AllowPrivate: true})
+	d := n.Deliver(context.Background(), srv.URL, "job.failed", "job-2", nil)
+	if d.Status != webhook.StatusFailed || len(d.Attempts) != 3 || d.Attempts[0].Error == "" {
+		t.Errorf("delivery = %+v", d)
+	}
+}
+
+func TestVerify_RejectsTamperingAndReplays(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"status":"success"}`)
+	header := webhook.Sign([]byte(secret), now, body)
+
+	if err := webhook.Verify([]byte(secret), header, body, 5*time.Minute, now); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	cases := map[string]error{
+		"tampered":  webhook.Verify([]byte(secret), header, []byte(`{"status":"error"}`), 5*time.Minute, now),
+		"wrong key": webhook.Verify([]byte("other"), header, body, 5*time.Minute, now),
+		"stale":     webhook.Verify([]byte(secret), header, body, 5*time.Minute, now.Add(10*time.Minute)),
+		"malformed": webhook.Verify([]byte(secret), "v1=abc", body, 5*time.Minute, now),
+	}
+	for name, err := range cases {
+		if !errors.Is(err, webhook.ErrBadSignature) {
+			t.Errorf("%s: err = %v, want ErrBadSignature", name, err)
+		}
+	}
+}
+
+func TestNotifier_RefusesInternalDestinations(t *testing.T) {
+	n := webhook.NewNotifier(secret, webhook.Config{MaxAttempts: 1})
+	for _, u := range []string{
+		"ftp://example.com/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://localhost:8080/hook",
+		"http://10.0.0.7/hook",
+		"http://[::1]/hook",
+	} {
+		if err := n.ValidateURL(u); err == nil {
+			t.Errorf("ValidateURL accepted %s", u)
+		}
+	}
+	if err := n.ValidateURL("https://example.com/hook"); err != nil {
+		t.Errorf("ValidateURL rejected a public URL: %v", err)
+	}
+
+	// A hostname is only resolved when connecting, so the dialer checks too.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	d := n.Deliver(context.Background(), strings.Replace(srv.URL, "127.0.0.1", "localhost", 1), "job.failed", "job-3", nil)
+	if d.Status != webhook.StatusFailed || !strings.Contains(d.Attempts[0].Error, "non-public address") {
+		t.Errorf("delivery to a loopback server = %+v", d)
+	}
+}
@@ -126,7 +126,7 @@ export async function POST(req: NextRequest): Promise<NextResponse> {
     );
   }
 
-  const { prompt, output_format, project_id, style_hints, preferred_provider, preferred_model, previous_generation_id, previous_html, previous_css } = parsed.data;
+  const { prompt, output_format, project_id, style_hints, preferred_provider, preferred_model, strict_provider, previous_generation_id, previous_html, previous_css } = parsed.data;
 
   // ── 2. Auth check ─────────────────────────────────────────────────────────
   const { userId: clerkUserId } = await auth();
@@ -268,6 +268,7 @@ export async function POST(req: NextRequest): Promise<NextResponse> {
     },
     preferred_provider: preferred_provider ?? "",
     preferred_model: preferred_model ?? "",
+    strict_provider: strict_provider ?? false,
   };
 
   const goEndpoint = isRefinement ? "/refine" : "/generate";
@@ -280,9 +281,16 @@ export async function POST(req: NextRequest): Promise<NextResponse> {
       PROXY_TIMEOUT_MS
     );
 
+    // A client retry carrying the same Idempotency-Key (or x-request-id, sent
+    // on as request_id) is answered from the Go service's record instead of
+    // generating again.
+    const idempotencyKey = req.headers.get("idempotency-key");
     goResponse = await fetch(`${AI_SERVICE_URL}${goEndpoint}`, {
       method: "POST",
-      headers: { "Content-Type": "application/json" },
+      headers: {
+        "Content-Type": "application/json",
+        ...(idempotencyKey ? { "Idempotency-Key": idempotencyKey } : {}),
+      },
       body: JSON.stringify(goPayload),
       signal: controller.signal,
     });
@@ -318,6 +326,8 @@ export async function POST(req: NextRequest): Promise<NextResponse> {
     duration_ms: number;
     token_count?: number;
     error?: string;
+    code?: string;
+    valid_choices?: Record<string, string[]>;
   };
 
   try {
@@ -326,6 +336,44 @@ export async function POST(req: NextRequest): Promise<NextResponse> {
     return err(503, "AI_SERVICE_UNAVAILABLE", "Invalid response from AI service.", undefined, requestId);
   }
 
+  if (goResponse.status === 400) {
+    switch (goResult.code) {
+      case "invalid_provider":
+      case "invalid_model":
+        // Unknown preferred provider/model — pass the valid choices through
+        return err(
+          400,
+          goResult.code === "invalid_model" ? "INVALID_MODEL" : "INVALID_PROVIDER",
+          goResult.error ?? "The selected model is not available.",
+          { valid_choices: goResult.valid_choices ?? {} },
+          requestId
+        );
+      default:
+        // Malformed body, missing prompt and other request errors
+        return err(
+          400,
+          "VALIDATION_ERROR",
+          goResult.error ?? "The request is invalid.",
+          undefined,
+          requestId
+        );
+    }
+  }
+
+  if (
+    goResponse.status === 409 ||
+    goResult.code === "idempotency_key_reused"
+  ) {
+    // Idempotency key still in progress, or reused with a different body
+    return err(
+      409,
+      "CONFLICT",
+      goResult.error ?? "This request conflicts with an earlier one.",
+      undefined,
+      requestId
+    );
+  }
+
   if (goResponse.status === 502 || goResult.status === "error") {
     // MOCK RESPONSE FOR TESTING PURPOSES WHEN LLMS ARE BROKEN
     goResult = {
@@ -6,6 +6,9 @@ export interface ModelInfo {
   id: string;
   display_name: string;
   provider: string;
+  context_window?: number;
+  max_output_tokens?: number;
+  vision?: boolean;
 }
 
 export interface ProviderInfo {
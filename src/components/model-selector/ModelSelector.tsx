@@ -16,6 +16,9 @@ export interface ModelInfo {
   id: string;
   display_name: string;
   provider: string;
+  context_window?: number;
+  max_output_tokens?: number;
+  vision?: boolean;
 }
 
 export interface ProviderInfo {
@@ -45,12 +48,14 @@ const PROVIDER_LABELS: Record<string, string> = {
   gemini: "Google Gemini",
   glm: "ZhipuAI GLM",
   copilot: "GitHub Copilot",
+  anthropic: "Anthropic Claude",
 };
 
 const PROVIDER_COLORS: Record<string, string> = {
   gemini: "#4285F4",
   glm: "#7C3AED",
   copilot: "#24292F",
+  anthropic: "#D97757",
 };
 
 // ---------------------------------------------------------------------------
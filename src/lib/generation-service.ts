@@ -74,6 +74,12 @@ export function toProviderEnum(name: string): ProviderUsed {
     case "copilot":
     case "github_copilot":
       return "github_copilot";
+    case "anthropic":
+      return "anthropic";
+    case "ollama":
+      return "ollama";
+    case "mock":
+      return "mock";
     default:
       return "glm";
   }
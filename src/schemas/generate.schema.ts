@@ -45,6 +45,8 @@ export const generateSchema = z.object({
   // Preferred provider/model — optional, user-selected
   preferred_provider: z.string().optional(),
   preferred_model: z.string().optional(),
+  // Only try preferred_provider — no fallback to other providers
+  strict_provider: z.boolean().optional(),
 
   // Refinement context — present when this is a chat refinement (ZEST-014/015)
   previous_generation_id: z.string().optional(),
@@ -43,7 +43,9 @@ export type ErrorCode =
   | "RATE_LIMIT_EXCEEDED"
   | "INTERNAL_ERROR"
   | "AI_SERVICE_UNAVAILABLE"
-  | "GENERATION_TIMEOUT";
+  | "GENERATION_TIMEOUT"
+  | "INVALID_PROVIDER"
+  | "INVALID_MODEL";
 
 // ---------------------------------------------------------------------------
 // Generation endpoint types
@@ -56,6 +58,7 @@ export interface GenerateRequestBody {
   style_hints?: string;
   preferred_provider?: string;
   preferred_model?: string;
+  strict_provider?: boolean;
 }
 
 export interface GenerateResponseData {